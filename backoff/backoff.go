@@ -29,19 +29,27 @@ package backoff
 
 import (
 	"context"
-	"math"
-	"math/rand"
+	"errors"
 	"sync/atomic"
 	"time"
 
+	"github.com/wtsi-ssg/wr/clock"
 	"github.com/wtsi-ssg/wr/clog"
 )
 
+// logSubsystem is the name of the clog.Named() Logger we log with.
+const logSubsystem = "backoff"
+
+// ErrSleepLimit is returned by SleepAtMost() when it cut a sleep short at its
+// limit.
+var ErrSleepLimit = errors.New("sleep cut short at limit")
+
 // Sleeper defines the Sleep method used by a Backoff.
 type Sleeper interface {
 	// Sleep sleeps for the given duration, stopping early if context is
-	// cancelled.
-	Sleep(context.Context, time.Duration)
+	// cancelled. It returns the context's error if the context ended before
+	// the duration elapsed, otherwise nil.
+	Sleep(context.Context, time.Duration) error
 }
 
 // Backoff is used to sleep for increasing periods of time.
@@ -53,58 +61,153 @@ type Backoff struct {
 	Max time.Duration
 
 	// Factor is the multiplying factor to apply to the time to sleep for on
-	// each Sleep() call.
+	// each Sleep() call, when Strategy is nil.
 	Factor float64
 
+	// Strategy determines how the time to sleep for changes with each Sleep()
+	// call. If nil, an Exponential Strategy using Factor is used.
+	Strategy Strategy
+
+	// Jitter determines how sleep times are randomised. The zero value is
+	// JitterCurrent.
+	Jitter JitterMode
+
 	// Sleeper is an implementation of Sleeper, to determine how the sleep
 	// actually happens.
 	Sleeper Sleeper
 
+	// Clock is used to tell how long is left before a context's deadline.
+	// Defaults to the clock from clock.FromContext().
+	Clock clock.Clock
+
 	sleeps uint64 // number of Sleep() calls in a row.
+	prev   int64  // duration of the previous sleep.
 }
 
 // Sleep will sleep (using Sleeper.Sleep()) for Min on the first call,
 // increasing the sleep duration by Factor up to Max on each subsequent call.
+// (Or as determined by Strategy, if set.)
 //
-// Sleep times in between Min and Max are jittered so multiple Backoffs working
-// at the same time don't all sleep for the same time periods.
+// Sleep times in between Min and Max are jittered according to Jitter, so
+// multiple Backoffs working at the same time don't all sleep for the same time
+// periods.
 //
-// If the supplied context is cancelled, we stop sleeping early.
+// If the supplied context is cancelled, we stop sleeping early. If it has a
+// deadline sooner than the end of the sleep, we only sleep until the deadline.
+// In both cases the context's error is returned, so you know not to try again;
+// otherwise nil is returned once the full duration has elapsed.
 //
-// Sleep durations are logged using the global context logger at debug level.
-func (b *Backoff) Sleep(ctx context.Context) {
+// Sleep durations are logged using the global context logger at debug level,
+// with the subsystem "backoff". Each sleep is also recorded as a
+// "backoff.sleep" span using clog.StartSpan().
+func (b *Backoff) Sleep(ctx context.Context) error {
+	return b.sleep(ctx, b.duration(), nil)
+}
+
+// SleepAtMost is like Sleep(), but sleeps for no longer than the given limit.
+// If the sleep would have been longer, we only sleep for the limit and then
+// return ErrSleepLimit (unless the context's deadline is even sooner, in which
+// case its error is returned as for Sleep()). This lets you stop at a time of
+// your own without making it a context deadline, which would be in real time
+// even if Sleeper uses some other clock.
+func (b *Backoff) SleepAtMost(ctx context.Context, limit time.Duration) error {
 	d := b.duration()
-	clog.Debug(ctx, "backoff", "sleep", d)
-	b.Sleeper.Sleep(ctx, d)
+	if d > limit {
+		return b.sleep(ctx, max(limit, 0), ErrSleepLimit)
+	}
+
+	return b.sleep(ctx, d, nil)
 }
 
-// duration calculates the next amount of time we should Sleep() for.
-func (b *Backoff) duration() time.Duration {
-	sleeps := atomic.AddUint64(&b.sleeps, 1) - 1
-	d := b.durationAfterSleeps(sleeps)
-	d = b.jitter(d, sleeps)
+// sleep implements Sleep() and SleepAtMost(), sleeping for d or until the
+// context's deadline if that is sooner. If the full d is slept for, limitErr is
+// returned.
+func (b *Backoff) sleep(ctx context.Context, d time.Duration, limitErr error) error {
+	d, capped := b.durationBeforeDeadline(ctx, d)
+	if capped {
+		limitErr = context.DeadlineExceeded
+	}
+
+	ctx, endSpan := clog.StartSpan(ctx, "backoff.sleep", "sleep", d)
+	clog.Named(logSubsystem).Debug(ctx, "backoff", "sleep", d)
+
+	err := b.Sleeper.Sleep(ctx, d)
+	if err == nil {
+		err = limitErr
+	}
+
+	endSpan(err)
+
+	return err
+}
+
+// durationBeforeDeadline returns d, or the time left according to our Clock
+// before the given context's deadline if that is shorter, in which case it
+// also returns true.
+func (b *Backoff) durationBeforeDeadline(ctx context.Context, d time.Duration) (time.Duration, bool) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return d, false
+	}
+
+	left := deadline.Sub(b.now(ctx))
+	if left >= d {
+		return d, false
+	}
 
-	return b.durationWithinBounds(d)
+	return max(left, 0), true
 }
 
-// durationAfterSleeps calculates the duration we should sleep for after the
-// given number of Sleep() calls.
-func (b *Backoff) durationAfterSleeps(sleeps uint64) time.Duration {
-	return time.Duration(float64(b.Min) * math.Pow(b.Factor, float64(sleeps)))
+// now returns the current time according to our Clock, or the given context's
+// clock if we don't have one.
+func (b *Backoff) now(ctx context.Context) time.Time {
+	if b.Clock == nil {
+		return clock.FromContext(ctx).Now()
+	}
+
+	return b.Clock.Now()
+}
+
+// NextDelay returns the duration that the next Sleep() would sleep for, and
+// advances to the following one, without sleeping. This lets you work out when
+// something may next be tried and move on, eg. the job queue using a Backoff
+// with a Min of 30s, Factor of 2 and Max of 1h to delay retries of failed jobs.
+//
+// It is safe to use concurrently with Sleep(), though the two will share the
+// same sequence of durations.
+func (b *Backoff) NextDelay() time.Duration {
+	return b.duration()
 }
 
-// jitter alters the given duration by subtracting a random amount of time from
-// it (but not so it is less than the previous unjittered sleep time). If
-// sleeps is 0 (there is no previous sleep time), applies no jitter, since
-// that would violate Min.
-func (b *Backoff) jitter(d time.Duration, sleeps uint64) time.Duration {
-	if sleeps == 0 {
-		return d
+// Next is like NextDelay(), but returns the time after now when the next
+// attempt should be made.
+func (b *Backoff) Next(now time.Time) time.Time {
+	return now.Add(b.NextDelay())
+}
+
+// duration calculates the next amount of time we should Sleep() for.
+func (b *Backoff) duration() time.Duration {
+	step := Step{
+		Sleeps: atomic.AddUint64(&b.sleeps, 1) - 1,
+		Prev:   time.Duration(atomic.LoadInt64(&b.prev)),
+		Min:    b.Min,
+		Max:    b.Max,
 	}
 
-	prev := b.durationAfterSleeps(sleeps - 1)
+	d := b.durationWithinBounds(b.Jitter.apply(b.strategy(), step))
+	atomic.StoreInt64(&b.prev, int64(d))
+
+	return d
+}
+
+// strategy returns our Strategy, or an Exponential one using our Factor if
+// that isn't set.
+func (b *Backoff) strategy() Strategy {
+	if b.Strategy != nil {
+		return b.Strategy
+	}
 
-	return time.Duration((rand.Float64() * float64(d-prev)) + float64(prev)) // #nosec
+	return Exponential{Factor: b.Factor}
 }
 
 // durationWithinBounds returns d but not less than Min and not more than Max.
@@ -123,4 +226,33 @@ func (b *Backoff) durationWithinBounds(d time.Duration) time.Duration {
 // Reset will cause the next Sleep() call to sleep for Min again.
 func (b *Backoff) Reset() {
 	atomic.StoreUint64(&b.sleeps, 0)
+	atomic.StoreInt64(&b.prev, 0)
+}
+
+// State is the position of a Backoff in its sequence of sleeps. It can be
+// serialised (eg. to JSON), so that something delayed by a Backoff can keep its
+// position across restarts.
+type State struct {
+	// Sleeps is the number of sleeps done since the Backoff was created or
+	// Reset().
+	Sleeps uint64 `json:"sleeps"`
+
+	// Prev is the duration of the previous sleep.
+	Prev time.Duration `json:"prev"`
+}
+
+// State returns our current State.
+func (b *Backoff) State() State {
+	return State{
+		Sleeps: atomic.LoadUint64(&b.sleeps),
+		Prev:   time.Duration(atomic.LoadInt64(&b.prev)),
+	}
+}
+
+// SetState sets our position in our sequence of sleeps to that of the given
+// State, which would normally have been previously returned by State() on a
+// Backoff with the same settings.
+func (b *Backoff) SetState(state State) {
+	atomic.StoreUint64(&b.sleeps, state.Sleeps)
+	atomic.StoreInt64(&b.prev, int64(state.Prev))
 }
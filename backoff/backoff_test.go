@@ -27,12 +27,15 @@ package backoff
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"sync"
 	"testing"
 	"time"
 
 	. "github.com/smartystreets/goconvey/convey"
 	"github.com/wtsi-ssg/wr/backoff/mock"
+	cm "github.com/wtsi-ssg/wr/clock/mock"
 	"github.com/wtsi-ssg/wr/clog"
 )
 
@@ -93,6 +96,58 @@ func TestBackoff(t *testing.T) {
 			})
 		})
 
+		Convey("Sleep() returns nil after the full duration", func() {
+			So(b.Sleep(ctx), ShouldBeNil)
+			So(sleeper.Elapsed(), ShouldEqual, 1*time.Millisecond)
+		})
+
+		Convey("Sleep() returns the context's error if it has ended", func() {
+			cctx, cancel := context.WithCancel(ctx)
+			cancel()
+			So(b.Sleep(cctx), ShouldEqual, context.Canceled)
+		})
+
+		Convey("Sleep() only sleeps until the context's deadline, then returns an error", func() {
+			b.Min = time.Hour
+			b.Max = time.Hour
+
+			dctx, cancel := context.WithTimeout(ctx, time.Second)
+			defer cancel()
+
+			So(errors.Is(b.Sleep(dctx), context.DeadlineExceeded), ShouldBeTrue)
+			So(sleeper.Elapsed(), ShouldBeLessThanOrEqualTo, time.Second)
+			So(sleeper.Elapsed(), ShouldBeGreaterThan, 0)
+		})
+
+		Convey("Sleep() works out the time left before the deadline using its Clock", func() {
+			b.Min = 2 * time.Hour
+			b.Max = 2 * time.Hour
+			b.Clock = cm.New(time.Now().Add(time.Hour))
+
+			dctx, cancel := context.WithDeadline(ctx, b.Clock.Now().Add(time.Second))
+			defer cancel()
+
+			So(errors.Is(b.Sleep(dctx), context.DeadlineExceeded), ShouldBeTrue)
+			So(sleeper.Elapsed(), ShouldBeLessThanOrEqualTo, time.Second)
+			So(sleeper.Elapsed(), ShouldBeGreaterThan, 0)
+		})
+
+		Convey("SleepAtMost() only sleeps up to its limit, then returns ErrSleepLimit", func() {
+			b.Min = time.Hour
+			b.Max = time.Hour
+
+			So(b.SleepAtMost(ctx, time.Second), ShouldEqual, ErrSleepLimit)
+			So(sleeper.Elapsed(), ShouldEqual, time.Second)
+
+			So(b.SleepAtMost(ctx, 2*time.Hour), ShouldBeNil)
+			So(sleeper.Elapsed(), ShouldEqual, time.Hour+time.Second)
+
+			dctx, cancel := context.WithTimeout(ctx, time.Millisecond)
+			defer cancel()
+
+			So(errors.Is(b.SleepAtMost(dctx, time.Second), context.DeadlineExceeded), ShouldBeTrue)
+		})
+
 		Convey("Sleep()s are logged", func() {
 			buff := clog.ToBufferAtLevel("debug")
 			defer clog.ToDefault()
@@ -100,6 +155,13 @@ func TestBackoff(t *testing.T) {
 			So(buff.String(), ShouldContainSubstring, "lvl=dbug")
 			So(buff.String(), ShouldContainSubstring, "msg=backoff")
 			So(buff.String(), ShouldContainSubstring, "sleep=1ms")
+			So(buff.String(), ShouldContainSubstring, "subsystem=backoff")
+		})
+
+		Convey("Sleep()s can be captured as records", func() {
+			captured := clog.Capture(t, "debug")
+			b.Sleep(ctx)
+			So(captured.HasRecord("debug", "backoff", "sleep", "1ms", "subsystem", "backoff"), ShouldBeTrue)
 		})
 	})
 
@@ -187,3 +249,51 @@ func TestBackoff(t *testing.T) {
 		So(base.Add(sleeper.Elapsed()), ShouldHappenOnOrBetween, base.Add(4*time.Millisecond), base.Add(5*time.Millisecond))
 	})
 }
+
+func TestBackoffNext(t *testing.T) {
+	Convey("Given a Backoff without a Sleeper", t, func() {
+		b := &Backoff{
+			Min:    30 * time.Second,
+			Max:    time.Hour,
+			Factor: 2,
+			Jitter: JitterNone,
+		}
+
+		Convey("NextDelay() returns successive sleep durations without sleeping", func() {
+			So(b.NextDelay(), ShouldEqual, 30*time.Second)
+			So(b.NextDelay(), ShouldEqual, time.Minute)
+			So(b.NextDelay(), ShouldEqual, 2*time.Minute)
+
+			Convey("Next() returns the time the next attempt should be made", func() {
+				now := time.Now()
+				So(b.Next(now), ShouldEqual, now.Add(4*time.Minute))
+
+				for range 10 {
+					b.Next(now)
+				}
+
+				So(b.Next(now), ShouldEqual, now.Add(time.Hour))
+
+				b.Reset()
+				So(b.Next(now), ShouldEqual, now.Add(30*time.Second))
+			})
+
+			Convey("State() can be serialised and restored to continue the sequence", func() {
+				state := b.State()
+				So(state, ShouldResemble, State{Sleeps: 3, Prev: 2 * time.Minute})
+
+				encoded, err := json.Marshal(state)
+				So(err, ShouldBeNil)
+
+				var decoded State
+				err = json.Unmarshal(encoded, &decoded)
+				So(err, ShouldBeNil)
+
+				restored := &Backoff{Min: b.Min, Max: b.Max, Factor: b.Factor, Jitter: JitterNone}
+				restored.SetState(decoded)
+				So(restored.NextDelay(), ShouldEqual, 4*time.Minute)
+				So(b.NextDelay(), ShouldEqual, 4*time.Minute)
+			})
+		})
+	})
+}
@@ -30,20 +30,33 @@ import (
 	"context"
 	"sync/atomic"
 	"time"
+
+	cm "github.com/wtsi-ssg/wr/clock/mock"
 )
 
 // Sleeper represents a mock implementation of backoff.Sleeper. It is
 // concurrent safe.
 type Sleeper struct {
+	// Clock, if set, is advanced by the duration of each Sleep(), so that
+	// code that checks the time sees the sleeps as having happened.
+	Clock *cm.Clock
+
 	sleepInvoked uint64
 	elapsed      int64
 }
 
-// Sleep increases Elapsed and increments SleepInvoked, but doesn't actually
-// sleep.
-func (s *Sleeper) Sleep(ctx context.Context, d time.Duration) {
+// Sleep increases Elapsed and increments SleepInvoked, and advances Clock if
+// set, but doesn't actually sleep. It returns the context's error, which will
+// be nil unless the context has already ended.
+func (s *Sleeper) Sleep(ctx context.Context, d time.Duration) error {
 	atomic.AddUint64(&s.sleepInvoked, 1)
 	atomic.AddInt64(&s.elapsed, int64(d))
+
+	if s.Clock != nil {
+		s.Clock.Advance(d)
+	}
+
+	return ctx.Err()
 }
 
 // Invoked returns the number of times Sleep() has been called.
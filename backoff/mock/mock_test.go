@@ -32,6 +32,7 @@ import (
 
 	. "github.com/smartystreets/goconvey/convey"
 	"github.com/wtsi-ssg/wr/backoff"
+	cm "github.com/wtsi-ssg/wr/clock/mock"
 )
 
 func TestSleeper(t *testing.T) {
@@ -55,5 +56,21 @@ func TestSleeper(t *testing.T) {
 		So(sleeper.Elapsed(), ShouldEqual, delay*2)
 
 		So(time.Now(), ShouldHappenBefore, tn.Add(delay))
+
+		Convey("but returns the error of an ended context", func() {
+			So(sleeper.Sleep(ctx, delay), ShouldBeNil)
+
+			cctx, cancel := context.WithCancel(ctx)
+			cancel()
+			So(sleeper.Sleep(cctx, delay), ShouldEqual, context.Canceled)
+		})
+	})
+
+	Convey("Sleeper.Sleep() advances its Clock, if set", t, func() {
+		tn := time.Now()
+		sleeper := &Sleeper{Clock: cm.New(tn)}
+
+		sleeper.Sleep(ctx, time.Hour)
+		So(sleeper.Clock.Now(), ShouldEqual, tn.Add(time.Hour))
 	})
 }
@@ -0,0 +1,164 @@
+/*******************************************************************************
+ * Copyright (c) 2026 Genome Research Ltd.
+ *
+ * Author: Sendu Bala <sb10@sanger.ac.uk>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining
+ * a copy of this software and associated documentation files (the
+ * "Software"), to deal in the Software without restriction, including
+ * without limitation the rights to use, copy, modify, merge, publish,
+ * distribute, sublicense, and/or sell copies of the Software, and to
+ * permit persons to whom the Software is furnished to do so, subject to
+ * the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included
+ * in all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ ******************************************************************************/
+
+package backoff
+
+// this file implements a registry of Backoffs shared by key.
+
+import (
+	"sync"
+	"time"
+
+	"github.com/wtsi-ssg/wr/clock"
+)
+
+// registryEntry is a Backoff in a Registry, along with when it was last used.
+type registryEntry struct {
+	backoff  *Backoff
+	lastUsed time.Time
+}
+
+// Registry hands out Backoffs shared by key, eg. a host:port or API endpoint,
+// so that many callers retrying the same thing back off together instead of
+// each on their own. It is safe for concurrent use.
+type Registry struct {
+	// New is called to make the Backoff for a key the first time it is
+	// needed (or after it expired).
+	New func(key string) *Backoff
+
+	// TTL is how long a key can go unused before its Backoff is forgotten. 0
+	// means keys never expire. It should be longer than the Max of the
+	// Backoffs.
+	TTL time.Duration
+
+	// Clock is used to tell how long keys have been unused. Defaults to real
+	// time.
+	Clock clock.Clock
+
+	mu        sync.Mutex
+	entries   map[string]*registryEntry
+	lastSweep time.Time
+}
+
+// Get returns the shared Backoff for the given key, making it with New() if
+// necessary.
+func (r *Registry) Get(key string) *Backoff {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if entry := r.entry(key); entry != nil {
+		return entry.backoff
+	}
+
+	b := r.New(key)
+	r.entries[key] = &registryEntry{backoff: b, lastUsed: r.now()}
+
+	return b
+}
+
+// entry returns the entry for the given key with its last use updated, or nil
+// if there isn't one or it has expired. Other expired entries are forgotten
+// at most once per TTL. You must hold the lock.
+func (r *Registry) entry(key string) *registryEntry {
+	now := r.now()
+
+	if r.entries == nil {
+		r.entries = make(map[string]*registryEntry)
+		r.lastSweep = now
+	}
+
+	if r.TTL > 0 && now.Sub(r.lastSweep) > r.TTL {
+		r.expire(now)
+	}
+
+	entry, found := r.entries[key]
+	if !found {
+		return nil
+	}
+
+	if r.expired(entry, now) {
+		delete(r.entries, key)
+
+		return nil
+	}
+
+	entry.lastUsed = now
+
+	return entry
+}
+
+// expired returns true if the given entry hasn't been used for longer than
+// TTL at the given time.
+func (r *Registry) expired(entry *registryEntry, now time.Time) bool {
+	return r.TTL > 0 && now.Sub(entry.lastUsed) > r.TTL
+}
+
+// expire forgets all entries that had expired at the given time. You must hold
+// the lock.
+func (r *Registry) expire(now time.Time) {
+	for key, entry := range r.entries {
+		if r.expired(entry, now) {
+			delete(r.entries, key)
+		}
+	}
+
+	r.lastSweep = now
+}
+
+// now returns the current time according to our Clock.
+func (r *Registry) now() time.Time {
+	if r.Clock == nil {
+		return time.Now()
+	}
+
+	return r.Clock.Now()
+}
+
+// Success Reset()s the shared Backoff for the given key, if there is one, so
+// that all callers using it go back to sleeping for Min.
+func (r *Registry) Success(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if entry := r.entry(key); entry != nil {
+		entry.backoff.Reset()
+	}
+}
+
+// Failure advances the shared Backoff for the given key to its next sleep
+// duration without sleeping, making it with New() if necessary.
+func (r *Registry) Failure(key string) {
+	r.Get(key).NextDelay()
+}
+
+// Len returns the number of keys that have an unexpired shared Backoff.
+func (r *Registry) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.expire(r.now())
+
+	return len(r.entries)
+}
@@ -0,0 +1,129 @@
+/*******************************************************************************
+ * Copyright (c) 2026 Genome Research Ltd.
+ *
+ * Author: Sendu Bala <sb10@sanger.ac.uk>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining
+ * a copy of this software and associated documentation files (the
+ * "Software"), to deal in the Software without restriction, including
+ * without limitation the rights to use, copy, modify, merge, publish,
+ * distribute, sublicense, and/or sell copies of the Software, and to
+ * permit persons to whom the Software is furnished to do so, subject to
+ * the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included
+ * in all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ ******************************************************************************/
+
+package backoff
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	cm "github.com/wtsi-ssg/wr/clock/mock"
+)
+
+func TestRegistry(t *testing.T) {
+	Convey("Given a Registry", t, func() {
+		mc := cm.New(time.Now())
+		made := 0
+		reg := &Registry{
+			New: func(key string) *Backoff {
+				made++
+
+				return &Backoff{Min: time.Millisecond, Max: 10 * time.Millisecond, Factor: 2}
+			},
+			TTL:   time.Minute,
+			Clock: mc,
+		}
+
+		Convey("Get() returns the same Backoff for the same key", func() {
+			b := reg.Get("a")
+			So(reg.Get("a"), ShouldEqual, b)
+			So(reg.Get("b"), ShouldNotEqual, b)
+			So(made, ShouldEqual, 2)
+			So(reg.Len(), ShouldEqual, 2)
+		})
+
+		Convey("Failure() advances and Success() resets the shared Backoff", func() {
+			reg.Failure("a")
+			reg.Failure("a")
+			So(reg.Get("a").State().Sleeps, ShouldEqual, 2)
+
+			reg.Success("a")
+			So(reg.Get("a").State().Sleeps, ShouldEqual, 0)
+
+			reg.Success("b")
+			So(reg.Len(), ShouldEqual, 1)
+		})
+
+		Convey("Keys unused for longer than TTL are forgotten", func() {
+			b := reg.Get("a")
+			reg.Get("b")
+
+			mc.Advance(40 * time.Second)
+			reg.Failure("a")
+			mc.Advance(40 * time.Second)
+			So(reg.Len(), ShouldEqual, 1)
+			So(reg.Get("a"), ShouldEqual, b)
+
+			mc.Advance(2 * time.Minute)
+			So(reg.Len(), ShouldEqual, 0)
+			So(reg.Get("a"), ShouldNotEqual, b)
+			So(made, ShouldEqual, 3)
+		})
+
+		Convey("Get() forgets an expired key, but only sweeps others once per TTL", func() {
+			b := reg.Get("a")
+			reg.Get("b")
+
+			mc.Advance(50 * time.Second)
+			reg.Get("c")
+			mc.Advance(20 * time.Second)
+			reg.Get("a")
+			So(len(reg.entries), ShouldEqual, 2)
+
+			mc.Advance(45 * time.Second)
+			reg.Get("a")
+			So(len(reg.entries), ShouldEqual, 2)
+
+			mc.Advance(20 * time.Second)
+			reg.Get("a")
+			So(len(reg.entries), ShouldEqual, 1)
+
+			mc.Advance(2 * time.Minute)
+			So(reg.Get("a"), ShouldNotEqual, b)
+		})
+
+		Convey("It can be used concurrently", func() {
+			var wg sync.WaitGroup
+
+			for range 10 {
+				wg.Add(1)
+
+				go func() {
+					defer wg.Done()
+
+					reg.Failure("a")
+					reg.Get("b")
+					reg.Success("b")
+				}()
+			}
+
+			wg.Wait()
+			So(reg.Get("a").State().Sleeps, ShouldEqual, 10)
+			So(made, ShouldEqual, 2)
+		})
+	})
+}
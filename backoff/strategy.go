@@ -0,0 +1,218 @@
+/*******************************************************************************
+ * Copyright (c) 2026 Genome Research Ltd.
+ *
+ * Author: Sendu Bala <sb10@sanger.ac.uk>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining
+ * a copy of this software and associated documentation files (the
+ * "Software"), to deal in the Software without restriction, including
+ * without limitation the rights to use, copy, modify, merge, publish,
+ * distribute, sublicense, and/or sell copies of the Software, and to
+ * permit persons to whom the Software is furnished to do so, subject to
+ * the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included
+ * in all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ ******************************************************************************/
+
+package backoff
+
+// this file implements the strategies and jitter modes a Backoff can use.
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+const decorrelatedMultiplier = 3
+
+// Step describes where a Backoff is in its sequence of sleeps, for use by a
+// Strategy.
+type Step struct {
+	// Sleeps is the number of Sleep() calls since the Backoff was created or
+	// Reset(), not including the current one.
+	Sleeps uint64
+
+	// Prev is the duration of the previous sleep, after jitter and bounds
+	// were applied, or 0 if there wasn't one.
+	Prev time.Duration
+
+	// Min and Max are those of the Backoff.
+	Min time.Duration
+	Max time.Duration
+}
+
+// Strategy determines how long a Backoff sleeps for.
+type Strategy interface {
+	// Delay returns the duration to sleep for at the given Step, before
+	// jitter is applied. The Backoff will limit the result to its Min and
+	// Max after applying jitter.
+	Delay(step Step) time.Duration
+}
+
+// Constant is a Strategy that always sleeps for Min.
+type Constant struct{}
+
+// Delay returns step.Min.
+func (Constant) Delay(step Step) time.Duration {
+	return step.Min
+}
+
+// Linear is a Strategy that sleeps for Min, then increases the sleep by
+// Increment each time.
+type Linear struct {
+	// Increment is added to the sleep duration each time. If 0, Min is used.
+	Increment time.Duration
+}
+
+// Delay returns step.Min plus step.Sleeps increments.
+func (l Linear) Delay(step Step) time.Duration {
+	increment := l.Increment
+	if increment == 0 {
+		increment = step.Min
+	}
+
+	return durationFromFloat(float64(step.Min) + float64(step.Sleeps)*float64(increment))
+}
+
+// Exponential is a Strategy that sleeps for Min, then multiplies the sleep by
+// Factor each time. It is the Strategy a Backoff uses by default.
+type Exponential struct {
+	Factor float64
+}
+
+// Delay returns step.Min multiplied by Factor to the power of step.Sleeps.
+func (e Exponential) Delay(step Step) time.Duration {
+	return durationFromFloat(float64(step.Min) * math.Pow(e.Factor, float64(step.Sleeps)))
+}
+
+// Fibonacci is a Strategy that sleeps for Min multiplied by successive
+// Fibonacci numbers: Min, Min, 2*Min, 3*Min, 5*Min and so on.
+type Fibonacci struct{}
+
+// Delay returns step.Min multiplied by the (step.Sleeps+1)th Fibonacci number,
+// stopping early once step.Max has been exceeded.
+func (Fibonacci) Delay(step Step) time.Duration {
+	if step.Min <= 0 {
+		return step.Min
+	}
+
+	prev, current := time.Duration(0), step.Min
+
+	for i := uint64(0); i < step.Sleeps && current <= step.Max; i++ {
+		prev, current = current, prev+current
+	}
+
+	return current
+}
+
+// randomised is implemented by Strategies whose Delay() is already random, so
+// that JitterCurrent doesn't further randomise them.
+type randomised interface {
+	randomised()
+}
+
+// Decorrelated is a Strategy that implements AWS-style "decorrelated jitter":
+// each sleep is a random duration between Min and 3 times the previous sleep,
+// capped at Max. Since it is already random, JitterCurrent (the default) leaves
+// its delays unaltered, as JitterNone does.
+type Decorrelated struct{}
+
+// randomised implements randomised.
+func (Decorrelated) randomised() {}
+
+// Delay returns a random duration between step.Min and 3 times step.Prev,
+// capped at step.Max.
+func (Decorrelated) Delay(step Step) time.Duration {
+	prev := step.Prev
+	if prev < step.Min {
+		prev = step.Min
+	}
+
+	d := randomBetween(step.Min, durationFromFloat(float64(prev)*decorrelatedMultiplier))
+	if step.Max > step.Min && d > step.Max {
+		return step.Max
+	}
+
+	return d
+}
+
+// JitterMode determines how a Backoff randomises the durations it gets from
+// its Strategy, so that multiple Backoffs working at the same time don't all
+// sleep for the same time periods.
+type JitterMode int
+
+const (
+	// JitterCurrent sleeps for a random duration between the Strategy's
+	// previous and current Delay(), applying no jitter to the first sleep. It
+	// is the default. Strategies that are already random, like Decorrelated,
+	// are not jittered further.
+	JitterCurrent JitterMode = iota
+
+	// JitterNone sleeps for exactly the Strategy's Delay().
+	JitterNone
+
+	// JitterFull sleeps for a random duration between 0 and the Strategy's
+	// Delay().
+	JitterFull
+
+	// JitterEqual sleeps for half the Strategy's Delay(), plus a random
+	// duration up to the other half.
+	JitterEqual
+)
+
+// apply returns the given strategy's delay at the given step, jittered
+// according to our mode.
+func (j JitterMode) apply(strategy Strategy, step Step) time.Duration {
+	d := strategy.Delay(step)
+
+	switch j {
+	case JitterNone:
+		return d
+	case JitterFull:
+		return randomBetween(0, d)
+	case JitterEqual:
+		return randomBetween(d/2, d) //nolint:mnd
+	default:
+		return randomBetweenSteps(strategy, step, d)
+	}
+}
+
+// randomBetweenSteps returns a random duration between the given strategy's
+// delay for the step before the given one, and the given delay for that step.
+// If there was no previous step, or the strategy is already random, returns
+// the given delay.
+func randomBetweenSteps(strategy Strategy, step Step, d time.Duration) time.Duration {
+	if _, ok := strategy.(randomised); ok || step.Sleeps == 0 {
+		return d
+	}
+
+	prevStep := step
+	prevStep.Sleeps--
+
+	return randomBetween(strategy.Delay(prevStep), d)
+}
+
+// randomBetween returns a random duration between lower and upper.
+func randomBetween(lower, upper time.Duration) time.Duration {
+	return time.Duration((rand.Float64() * float64(upper-lower)) + float64(lower)) // #nosec
+}
+
+// durationFromFloat converts the given number of nanoseconds to a Duration,
+// capping it at the maximum possible Duration.
+func durationFromFloat(ns float64) time.Duration {
+	if ns >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+
+	return time.Duration(ns)
+}
@@ -0,0 +1,160 @@
+/*******************************************************************************
+ * Copyright (c) 2026 Genome Research Ltd.
+ *
+ * Author: Sendu Bala <sb10@sanger.ac.uk>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining
+ * a copy of this software and associated documentation files (the
+ * "Software"), to deal in the Software without restriction, including
+ * without limitation the rights to use, copy, modify, merge, publish,
+ * distribute, sublicense, and/or sell copies of the Software, and to
+ * permit persons to whom the Software is furnished to do so, subject to
+ * the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included
+ * in all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ ******************************************************************************/
+
+package backoff
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/wtsi-ssg/wr/backoff/mock"
+)
+
+// delays returns the delays of the given strategy for the first n steps, with
+// each step's Prev being the previous delay.
+func delays(s Strategy, n int, min, max time.Duration) []time.Duration {
+	ds := make([]time.Duration, n)
+
+	var prev time.Duration
+
+	for i := range ds {
+		ds[i] = s.Delay(Step{Sleeps: uint64(i), Prev: prev, Min: min, Max: max})
+		prev = ds[i]
+	}
+
+	return ds
+}
+
+func TestStrategies(t *testing.T) {
+	ms := time.Millisecond
+
+	Convey("Strategies return the expected delays", t, func() {
+		So(delays(Constant{}, 3, ms, 10*ms), ShouldResemble, []time.Duration{ms, ms, ms})
+		So(delays(Linear{}, 3, ms, 10*ms), ShouldResemble, []time.Duration{ms, 2 * ms, 3 * ms})
+		So(delays(Linear{Increment: 5 * ms}, 3, ms, 10*ms), ShouldResemble, []time.Duration{ms, 6 * ms, 11 * ms})
+		So(delays(Exponential{Factor: 2}, 4, ms, 10*ms), ShouldResemble, []time.Duration{ms, 2 * ms, 4 * ms, 8 * ms})
+		So(delays(Fibonacci{}, 6, ms, 10*ms), ShouldResemble, []time.Duration{ms, ms, 2 * ms, 3 * ms, 5 * ms, 8 * ms})
+		So(delays(Fibonacci{}, 2, 0, 10*ms), ShouldResemble, []time.Duration{0, 0})
+
+		for _, d := range delays(Decorrelated{}, 10, ms, 10*ms) {
+			So(d, ShouldBeBetweenOrEqual, ms, 10*ms)
+		}
+
+		d := Decorrelated{}.Delay(Step{Prev: 4 * ms, Min: ms, Max: 100 * ms})
+		So(d, ShouldBeBetweenOrEqual, ms, 12*ms)
+	})
+
+	Convey("Strategies don't overflow after many sleeps", t, func() {
+		for _, s := range []Strategy{Linear{}, Exponential{Factor: 2}, Fibonacci{}} {
+			d := s.Delay(Step{Sleeps: math.MaxUint64, Min: ms, Max: 10 * ms})
+			So(d, ShouldBeGreaterThan, 10*ms)
+		}
+	})
+
+	Convey("Jitter modes randomise delays as expected", t, func() {
+		step := Step{Sleeps: 2, Min: ms, Max: 100 * ms}
+		exp := Exponential{Factor: 2}
+
+		So(JitterNone.apply(exp, step), ShouldEqual, 4*ms)
+
+		for range 20 {
+			So(JitterFull.apply(exp, step), ShouldBeBetweenOrEqual, 0, 4*ms)
+			So(JitterEqual.apply(exp, step), ShouldBeBetweenOrEqual, 2*ms, 4*ms)
+			So(JitterCurrent.apply(exp, step), ShouldBeBetweenOrEqual, 2*ms, 4*ms)
+		}
+
+		So(JitterCurrent.apply(exp, Step{Min: ms, Max: 100 * ms}), ShouldEqual, ms)
+
+		Convey("except that JitterCurrent leaves Decorrelated delays within Min and 3 times Prev", func() {
+			sleeper := &mock.Sleeper{}
+			b := &Backoff{Min: ms, Max: time.Hour, Strategy: Decorrelated{}, Sleeper: sleeper}
+
+			elapsed := time.Duration(0)
+
+			for range 20 {
+				prev := b.State().Prev
+				b.Sleep(context.Background())
+				d := sleeper.Elapsed() - elapsed
+				elapsed = sleeper.Elapsed()
+				So(d, ShouldBeBetweenOrEqual, ms, 3*max(prev, ms))
+			}
+
+			counter := &countingDecorrelated{}
+			JitterCurrent.apply(counter, Step{Sleeps: 2, Prev: 4 * ms, Min: ms, Max: 100 * ms})
+			So(counter.calls, ShouldEqual, 1)
+		})
+	})
+
+	Convey("A Backoff can use any Strategy and JitterMode, staying within Min and Max", t, func() {
+		ctx := context.Background()
+
+		for _, s := range []Strategy{Constant{}, Linear{}, Exponential{Factor: 3}, Fibonacci{}, Decorrelated{}} {
+			for _, j := range []JitterMode{JitterCurrent, JitterNone, JitterFull, JitterEqual} {
+				sleeper := &mock.Sleeper{}
+				b := &Backoff{Min: 2 * ms, Max: 10 * ms, Strategy: s, Jitter: j, Sleeper: sleeper}
+
+				prev := time.Duration(0)
+
+				for range 10 {
+					b.Sleep(ctx)
+					d := sleeper.Elapsed() - prev
+					prev = sleeper.Elapsed()
+					So(d, ShouldBeBetweenOrEqual, 2*ms, 10*ms)
+				}
+			}
+		}
+
+		Convey("and without jitter sleeps for exactly the Strategy's delays", func() {
+			sleeper := &mock.Sleeper{}
+			b := &Backoff{Min: ms, Max: 10 * ms, Strategy: Fibonacci{}, Jitter: JitterNone, Sleeper: sleeper}
+
+			for range 7 {
+				b.Sleep(ctx)
+			}
+
+			So(sleeper.Elapsed(), ShouldEqual, (1+1+2+3+5+8+10)*ms)
+
+			b.Reset()
+			b.Sleep(ctx)
+			So(sleeper.Elapsed(), ShouldEqual, (1+1+2+3+5+8+10+1)*ms)
+		})
+	})
+}
+
+// countingDecorrelated is a Decorrelated Strategy that counts calls to Delay().
+type countingDecorrelated struct {
+	Decorrelated
+	calls int
+}
+
+// Delay counts the call and returns Decorrelated's delay.
+func (c *countingDecorrelated) Delay(step Step) time.Duration {
+	c.calls++
+
+	return c.Decorrelated.Delay(step)
+}
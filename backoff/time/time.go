@@ -31,6 +31,7 @@ import (
 	"time"
 
 	"github.com/wtsi-ssg/wr/backoff"
+	"github.com/wtsi-ssg/wr/clock"
 )
 
 const (
@@ -40,18 +41,34 @@ const (
 )
 
 // Sleeper represents an implementation of backoff.Sleeper. It does an actual
-// sleep using time.Sleep.
-type Sleeper struct{}
+// sleep using a clock.Clock.
+type Sleeper struct {
+	// Clock is used to sleep. If nil, the Clock of the context passed to
+	// Sleep() is used (see clock.FromContext()), which defaults to real time.
+	Clock clock.Clock
+}
 
 // Sleep sleeps until the context is cancelled, or the given duration has
-// elapsed.
-func (s *Sleeper) Sleep(ctx context.Context, d time.Duration) {
+// elapsed. It returns the context's error in the former case, nil otherwise.
+func (s *Sleeper) Sleep(ctx context.Context, d time.Duration) error {
+	timer := s.clock(ctx).NewTimer(d)
+	defer timer.Stop()
+
 	select {
-	case <-time.After(d):
-		return
+	case <-timer.C():
+		return nil
 	case <-ctx.Done():
-		return
+		return ctx.Err()
+	}
+}
+
+// clock returns our Clock, or the given context's.
+func (s *Sleeper) clock(ctx context.Context) clock.Clock {
+	if s.Clock != nil {
+		return s.Clock
 	}
+
+	return clock.FromContext(ctx)
 }
 
 // SecondsRangeBackoff returns a ready-to-use, generally useful backoff.Backoff
@@ -27,11 +27,14 @@ package time
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
 	. "github.com/smartystreets/goconvey/convey"
 	"github.com/wtsi-ssg/wr/backoff"
+	"github.com/wtsi-ssg/wr/clock"
+	cm "github.com/wtsi-ssg/wr/clock/mock"
 )
 
 func TestSleeper(t *testing.T) {
@@ -44,7 +47,7 @@ func TestSleeper(t *testing.T) {
 		tn := time.Now()
 		delay := 1 * time.Millisecond
 
-		sleeper.Sleep(context.Background(), delay)
+		So(sleeper.Sleep(context.Background(), delay), ShouldBeNil)
 		So(time.Now(), ShouldHappenOnOrAfter, tn.Add(delay))
 	})
 
@@ -57,10 +60,42 @@ func TestSleeper(t *testing.T) {
 		ctx, cancel := context.WithTimeout(context.Background(), cancelAfter)
 		defer cancel()
 
-		sleeper.Sleep(ctx, delay)
+		err := sleeper.Sleep(ctx, delay)
+		So(errors.Is(err, context.DeadlineExceeded), ShouldBeTrue)
 		So(time.Now(), ShouldHappenBetween, tn.Add(cancelAfter), tn.Add(500*time.Millisecond))
 	})
 
+	Convey("Sleeper.Sleep() uses a Clock, from the context by default", t, func() {
+		mc := cm.New(time.Now())
+		done := make(chan bool)
+
+		for _, sleeper := range []*Sleeper{{Clock: mc}, {}} {
+			go func() {
+				sleeper.Sleep(clock.ContextWithClock(context.Background(), mc), time.Hour)
+				done <- true
+			}()
+
+			mc.BlockUntil(1)
+			mc.Advance(time.Hour)
+			So(<-done, ShouldBeTrue)
+			So(mc.Waiters(), ShouldEqual, 0)
+		}
+
+		Convey("stopping its timer if the context is cancelled", func() {
+			ctx, cancel := context.WithCancel(clock.ContextWithClock(context.Background(), mc))
+
+			go func() {
+				(&Sleeper{}).Sleep(ctx, time.Hour)
+				done <- true
+			}()
+
+			mc.BlockUntil(1)
+			cancel()
+			So(<-done, ShouldBeTrue)
+			So(mc.Waiters(), ShouldEqual, 0)
+		})
+	})
+
 	Convey("SecondsRangeBackoff returns a generally useful Backoff in the seconds range", t, func() {
 		b := SecondsRangeBackoff()
 		So(b.Min, ShouldEqual, secondsRangeMin)
@@ -0,0 +1,449 @@
+/*******************************************************************************
+ * Copyright (c) 2026 Genome Research Ltd.
+ *
+ * Author: Sendu Bala <sb10@sanger.ac.uk>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining
+ * a copy of this software and associated documentation files (the
+ * "Software"), to deal in the Software without restriction, including
+ * without limitation the rights to use, copy, modify, merge, publish,
+ * distribute, sublicense, and/or sell copies of the Software, and to
+ * permit persons to whom the Software is furnished to do so, subject to
+ * the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included
+ * in all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ ******************************************************************************/
+
+// package circuitbreaker is used to stop trying to use something, eg. the docker
+// daemon or a cloud API, for a while after it keeps failing.
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/wtsi-ssg/wr/backoff"
+	"github.com/wtsi-ssg/wr/clock"
+	"github.com/wtsi-ssg/wr/clog"
+	"github.com/wtsi-ssg/wr/retry"
+)
+
+const (
+	// logSubsystem is the name of the clog.Named() Logger we log with.
+	logSubsystem = "circuitbreaker"
+
+	defaultCoolDownMin    = 1 * time.Second
+	defaultCoolDownMax    = 1 * time.Minute
+	defaultCoolDownFactor = 2
+)
+
+// ErrOpen is returned by Allow() and Do() when the Breaker is open.
+var ErrOpen = errors.New("circuit breaker is open")
+
+// State is the state of a Breaker.
+type State int
+
+// State* constants are the states a Breaker can be in.
+const (
+	// StateClosed means calls are allowed.
+	StateClosed State = iota
+
+	// StateOpen means calls are not allowed, until the cool-down is over.
+	StateOpen
+
+	// StateHalfOpen means one trial call is allowed, to see if things are
+	// working again.
+	StateHalfOpen
+)
+
+// String returns "closed", "open" or "half-open".
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	default:
+		return "half-open"
+	}
+}
+
+// Generation identifies the calls allowed by a Breaker while it was in a
+// particular state. It is returned by Allow() and must be passed to Record().
+type Generation uint64
+
+// stateChange records that a Breaker changed state. The zero value means there
+// was no change.
+type stateChange struct {
+	from State
+	to   State
+}
+
+// Breaker is a circuit breaker. It starts closed, allowing calls. When calls
+// fail too much it trips open, and calls are rejected with ErrOpen until a
+// cool-down has passed. It then goes half-open, allowing a single trial call:
+// if that succeeds it closes again, otherwise it re-opens for a longer
+// cool-down.
+//
+// Set ConsecutiveFailures and/or FailureRate to determine when it trips; if
+// neither is set it never trips. Don't change its fields after first use. It
+// is safe to share between goroutines.
+//
+// If you use Allow() instead of Do(), you must Record() the outcome of every
+// call it allows; see Allow() for what happens if you don't.
+type Breaker struct {
+	// Name identifies the Breaker in logs.
+	Name string
+
+	// ConsecutiveFailures, if greater than 0, trips the Breaker when this many
+	// calls in a row fail.
+	ConsecutiveFailures int
+
+	// FailureRate, if greater than 0, trips the Breaker when a call fails and
+	// at least this fraction (0..1) of the last Window calls failed.
+	FailureRate float64
+
+	// Window is the number of most recent calls FailureRate applies to. The
+	// rate isn't considered until there have been this many calls.
+	Window int
+
+	// CoolDown determines how long the Breaker stays open: its NextDelay() is
+	// used each time it opens, and it is Reset() when the Breaker closes.
+	// Defaults to a Backoff from 1s to 1m with a Factor of 2.
+	CoolDown *backoff.Backoff
+
+	// Clock is used to tell when the cool-down is over. Defaults to real time.
+	Clock clock.Clock
+
+	// OnStateChange, if set, is called after the Breaker changes state, with
+	// the context of the call that caused the change. State changes are
+	// always logged using the global context logger, with the subsystem
+	// "circuitbreaker".
+	OnStateChange func(ctx context.Context, name string, from, to State)
+
+	mu          sync.Mutex
+	state       State
+	consecutive int
+	outcomes    []bool
+	next        int
+	recorded    int
+	failures    int
+	openUntil   time.Time
+	openedFor   time.Duration
+	probing     bool
+	probeUntil  time.Time
+	generation  Generation
+}
+
+// Allow returns nil if a call may be made now, or ErrOpen if not. When nil is
+// returned, you must later tell us the outcome of the call with Record(),
+// passing it the returned Generation.
+//
+// Outcomes of calls from an older Generation are ignored, so that eg. a slow
+// call allowed while we were closed can't close us again while we're
+// half-open.
+//
+// This matters most when we're half-open: the call allowed then is the single
+// trial call, and all other calls are rejected until its outcome is recorded.
+// If it never is (eg. because the caller gave up), the trial is abandoned
+// after as long as the cool-down that preceded it, and another trial call is
+// allowed.
+func (b *Breaker) Allow(ctx context.Context) (Generation, error) {
+	b.mu.Lock()
+	change, err := b.allow()
+	gen := b.generation
+	b.mu.Unlock()
+
+	b.notify(ctx, change)
+
+	return gen, err
+}
+
+// allow implements Allow(). You must hold the lock.
+func (b *Breaker) allow() (stateChange, error) {
+	switch b.state {
+	case StateClosed:
+		return stateChange{}, nil
+	case StateOpen:
+		if b.now().Before(b.openUntil) {
+			return stateChange{}, ErrOpen
+		}
+
+		b.startProbe()
+
+		return b.setState(StateHalfOpen), nil
+	default:
+		if b.probeRunning() {
+			return stateChange{}, ErrOpen
+		}
+
+		b.startProbe()
+
+		return stateChange{}, nil
+	}
+}
+
+// startProbe notes that the half-open trial call is being made, giving it as
+// long as our last cool-down to have its outcome recorded. It starts a new
+// Generation, so that an abandoned trial call's outcome is ignored. You must
+// hold the lock.
+func (b *Breaker) startProbe() {
+	b.generation++
+	b.probing = true
+	b.probeUntil = b.now().Add(b.openedFor)
+}
+
+// probeRunning returns true if a half-open trial call is being made and hasn't
+// been abandoned. You must hold the lock.
+func (b *Breaker) probeRunning() bool {
+	return b.probing && b.now().Before(b.probeUntil)
+}
+
+// Record tells us the outcome of a call allowed by Allow(), which returned the
+// given Generation: a nil err is a success, anything else a failure. This may
+// change our State, unless the Generation is no longer current.
+func (b *Breaker) Record(ctx context.Context, gen Generation, err error) {
+	b.mu.Lock()
+	change := b.record(gen, err)
+	b.mu.Unlock()
+
+	b.notify(ctx, change)
+}
+
+// record implements Record(). You must hold the lock.
+func (b *Breaker) record(gen Generation, err error) stateChange {
+	if gen != b.generation {
+		return stateChange{}
+	}
+
+	if err == nil {
+		return b.succeeded()
+	}
+
+	return b.failed()
+}
+
+// succeeded records a successful call, closing if it was the half-open trial.
+// You must hold the lock.
+func (b *Breaker) succeeded() stateChange {
+	b.consecutive = 0
+
+	if b.state == StateHalfOpen {
+		b.probing = false
+		b.coolDown().Reset()
+
+		return b.setState(StateClosed)
+	}
+
+	b.recordOutcome(false)
+
+	return stateChange{}
+}
+
+// failed records a failed call, opening if that trips us or it was the
+// half-open trial. You must hold the lock.
+func (b *Breaker) failed() stateChange {
+	if b.state == StateOpen {
+		return stateChange{}
+	}
+
+	if b.state == StateHalfOpen {
+		b.probing = false
+
+		return b.open()
+	}
+
+	b.consecutive++
+	b.recordOutcome(true)
+
+	if b.tripped() {
+		return b.open()
+	}
+
+	return stateChange{}
+}
+
+// recordOutcome adds an outcome to our window of recent calls. You must hold
+// the lock.
+func (b *Breaker) recordOutcome(failed bool) {
+	if b.Window <= 0 {
+		return
+	}
+
+	if b.outcomes == nil {
+		b.outcomes = make([]bool, b.Window)
+	}
+
+	if b.recorded == b.Window {
+		if b.outcomes[b.next] {
+			b.failures--
+		}
+	} else {
+		b.recorded++
+	}
+
+	b.outcomes[b.next] = failed
+	if failed {
+		b.failures++
+	}
+
+	b.next = (b.next + 1) % b.Window
+}
+
+// tripped returns true if recent failures mean we should open. You must hold
+// the lock.
+func (b *Breaker) tripped() bool {
+	if b.ConsecutiveFailures > 0 && b.consecutive >= b.ConsecutiveFailures {
+		return true
+	}
+
+	return b.FailureRate > 0 && b.Window > 0 && b.recorded == b.Window &&
+		float64(b.failures)/float64(b.recorded) >= b.FailureRate
+}
+
+// open opens us for our next cool-down, forgetting recent outcomes. You must
+// hold the lock.
+func (b *Breaker) open() stateChange {
+	b.openedFor = b.coolDown().NextDelay()
+	b.openUntil = b.now().Add(b.openedFor)
+	b.consecutive = 0
+	b.outcomes = nil
+	b.next, b.recorded, b.failures = 0, 0, 0
+
+	return b.setState(StateOpen)
+}
+
+// coolDown returns our CoolDown, setting it to the default if unset. You must
+// hold the lock.
+func (b *Breaker) coolDown() *backoff.Backoff {
+	if b.CoolDown == nil {
+		b.CoolDown = &backoff.Backoff{
+			Min:    defaultCoolDownMin,
+			Max:    defaultCoolDownMax,
+			Factor: defaultCoolDownFactor,
+		}
+	}
+
+	return b.CoolDown
+}
+
+// setState changes our state, starting a new Generation, and returns the
+// change. You must hold the lock.
+func (b *Breaker) setState(state State) stateChange {
+	change := stateChange{from: b.state, to: state}
+	b.state = state
+	b.generation++
+
+	return change
+}
+
+// now returns the current time according to our Clock.
+func (b *Breaker) now() time.Time {
+	if b.Clock == nil {
+		return time.Now()
+	}
+
+	return b.Clock.Now()
+}
+
+// notify logs the given change, if there was one, and calls OnStateChange.
+// Logging is at warn level when opening, info otherwise.
+func (b *Breaker) notify(ctx context.Context, change stateChange) {
+	if change.from == change.to {
+		return
+	}
+
+	logger := clog.Named(logSubsystem)
+	args := []interface{}{"breaker", b.Name, "from", change.from.String(), "to", change.to.String()}
+
+	if change.to == StateOpen {
+		logger.Warn(ctx, "circuit breaker state changed", args...)
+	} else {
+		logger.Info(ctx, "circuit breaker state changed", args...)
+	}
+
+	if b.OnStateChange != nil {
+		b.OnStateChange(ctx, b.Name, change.from, change.to)
+	}
+}
+
+// State returns our current State. An open Breaker whose cool-down is over is
+// reported as half-open, since the next call will be allowed as a trial.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateOpen && !b.now().Before(b.openUntil) {
+		return StateHalfOpen
+	}
+
+	return b.state
+}
+
+// rejecting returns true if a call made now would get ErrOpen.
+func (b *Breaker) rejecting() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		return b.now().Before(b.openUntil)
+	case StateHalfOpen:
+		return b.probeRunning()
+	default:
+		return false
+	}
+}
+
+// Do calls op if Allow()ed, Record()ing its outcome and returning its error.
+// Returns ErrOpen without calling op if not allowed.
+func (b *Breaker) Do(ctx context.Context, op retry.Operation) error {
+	gen, err := b.Allow(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = op()
+	b.Record(ctx, gen, err)
+
+	return err
+}
+
+// Wrap returns a retry.Operation that calls Do() with the given op, for use
+// with retry.Do() along with our Until, eg.
+//
+//	status := retry.Do(ctx, breaker.Wrap(ctx, op),
+//		retry.Untils{&circuitbreaker.Until{Breaker: breaker}, &retry.UntilLimit{Max: 5}},
+//		bo, "listing containers")
+func (b *Breaker) Wrap(ctx context.Context, op retry.Operation) retry.Operation {
+	return func() error {
+		return b.Do(ctx, op)
+	}
+}
+
+// Until implements retry.Until, stopping retries while its Breaker is open.
+type Until struct {
+	Breaker *Breaker
+}
+
+// ShouldStop returns retry.BecauseCircuitOpen when the next attempt would be
+// rejected by our Breaker, otherwise a blank Reason. retries and err are not
+// considered.
+func (u *Until) ShouldStop(retries int, err error) retry.Reason {
+	if u.Breaker.rejecting() {
+		return retry.BecauseCircuitOpen
+	}
+
+	return ""
+}
@@ -0,0 +1,279 @@
+/*******************************************************************************
+ * Copyright (c) 2026 Genome Research Ltd.
+ *
+ * Author: Sendu Bala <sb10@sanger.ac.uk>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining
+ * a copy of this software and associated documentation files (the
+ * "Software"), to deal in the Software without restriction, including
+ * without limitation the rights to use, copy, modify, merge, publish,
+ * distribute, sublicense, and/or sell copies of the Software, and to
+ * permit persons to whom the Software is furnished to do so, subject to
+ * the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included
+ * in all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ ******************************************************************************/
+
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/wtsi-ssg/wr/backoff"
+	bm "github.com/wtsi-ssg/wr/backoff/mock"
+	cm "github.com/wtsi-ssg/wr/clock/mock"
+	"github.com/wtsi-ssg/wr/clog"
+	"github.com/wtsi-ssg/wr/retry"
+)
+
+var errFail = errors.New("fail")
+
+// fail is an operation that always fails.
+func fail() error { return errFail }
+
+// succeed is an operation that always succeeds.
+func succeed() error { return nil }
+
+func TestBreaker(t *testing.T) {
+	background := context.Background()
+
+	Convey("Given a Breaker that trips after consecutive failures", t, func() {
+		mc := cm.New(time.Now())
+
+		var (
+			mu      sync.Mutex
+			changes []string
+		)
+
+		b := &Breaker{
+			Name:                "docker",
+			ConsecutiveFailures: 3,
+			CoolDown:            &backoff.Backoff{Min: time.Minute, Max: time.Hour, Factor: 2, Jitter: backoff.JitterNone},
+			Clock:               mc,
+			OnStateChange: func(ctx context.Context, name string, from, to State) {
+				mu.Lock()
+				defer mu.Unlock()
+
+				changes = append(changes, name+":"+from.String()+">"+to.String())
+			},
+		}
+		captured := clog.Capture(t, "info")
+
+		So(b.State(), ShouldEqual, StateClosed)
+		So(b.Do(background, fail), ShouldEqual, errFail)
+		So(b.Do(background, fail), ShouldEqual, errFail)
+		So(b.Do(background, succeed), ShouldBeNil)
+		So(b.Do(background, fail), ShouldEqual, errFail)
+		So(b.Do(background, fail), ShouldEqual, errFail)
+		So(b.State(), ShouldEqual, StateClosed)
+
+		So(b.Do(background, fail), ShouldEqual, errFail)
+		So(b.State(), ShouldEqual, StateOpen)
+		So(changes, ShouldResemble, []string{"docker:closed>open"})
+		So(captured.HasRecord("warn", "circuit breaker state changed",
+			"breaker", "docker", "from", "closed", "to", "open", "subsystem", "circuitbreaker"), ShouldBeTrue)
+
+		Convey("it rejects calls while open", func() {
+			called := false
+			err := b.Do(background, func() error {
+				called = true
+
+				return nil
+			})
+			So(err, ShouldEqual, ErrOpen)
+			So(called, ShouldBeFalse)
+
+			mc.Advance(59 * time.Second)
+			So(allowErr(b), ShouldEqual, ErrOpen)
+		})
+
+		Convey("after the cool-down it allows a single trial call", func() {
+			mc.Advance(time.Minute)
+			So(b.State(), ShouldEqual, StateHalfOpen)
+			gen, err := b.Allow(background)
+			So(err, ShouldBeNil)
+			So(allowErr(b), ShouldEqual, ErrOpen)
+			So(changes, ShouldResemble, []string{"docker:closed>open", "docker:open>half-open"})
+
+			Convey("closing if it succeeds", func() {
+				b.Record(background, gen, nil)
+				So(b.State(), ShouldEqual, StateClosed)
+				So(b.Do(background, succeed), ShouldBeNil)
+				So(changes[2], ShouldEqual, "docker:half-open>closed")
+				So(captured.HasRecord("info", "circuit breaker state changed",
+					"from", "half-open", "to", "closed"), ShouldBeTrue)
+				So(b.CoolDown.State().Sleeps, ShouldEqual, 0)
+			})
+
+			Convey("re-opening for longer if it fails", func() {
+				b.Record(background, gen, errFail)
+				So(b.State(), ShouldEqual, StateOpen)
+
+				mc.Advance(time.Minute)
+				So(b.State(), ShouldEqual, StateOpen)
+
+				mc.Advance(time.Minute)
+				So(b.State(), ShouldEqual, StateHalfOpen)
+			})
+
+			Convey("allowing another if its outcome isn't recorded within the cool-down", func() {
+				until := &Until{Breaker: b}
+
+				mc.Advance(59 * time.Second)
+				So(allowErr(b), ShouldEqual, ErrOpen)
+				So(until.ShouldStop(0, nil), ShouldEqual, retry.BecauseCircuitOpen)
+
+				mc.Advance(time.Second)
+				So(until.ShouldStop(0, nil), ShouldEqual, retry.Reason(""))
+				gen2, err := b.Allow(background)
+				So(err, ShouldBeNil)
+				So(allowErr(b), ShouldEqual, ErrOpen)
+
+				b.Record(background, gen, errFail)
+				So(b.State(), ShouldEqual, StateHalfOpen)
+
+				b.Record(background, gen2, nil)
+				So(b.State(), ShouldEqual, StateClosed)
+			})
+		})
+	})
+
+	Convey("A Breaker ignores late outcomes of calls allowed in an earlier state", t, func() {
+		mc := cm.New(time.Now())
+		b := &Breaker{
+			ConsecutiveFailures: 1,
+			CoolDown:            &backoff.Backoff{Min: time.Minute, Max: time.Minute, Factor: 1},
+			Clock:               mc,
+		}
+
+		genA, err := b.Allow(background)
+		So(err, ShouldBeNil)
+		genB, err := b.Allow(background)
+		So(err, ShouldBeNil)
+
+		b.Record(background, genB, errFail)
+		So(b.State(), ShouldEqual, StateOpen)
+
+		mc.Advance(time.Minute)
+		genC, err := b.Allow(background)
+		So(err, ShouldBeNil)
+		So(b.State(), ShouldEqual, StateHalfOpen)
+
+		b.Record(background, genA, nil)
+		So(b.State(), ShouldEqual, StateHalfOpen)
+		So(allowErr(b), ShouldEqual, ErrOpen)
+
+		b.Record(background, genA, errFail)
+		So(b.State(), ShouldEqual, StateHalfOpen)
+
+		b.Record(background, genC, nil)
+		So(b.State(), ShouldEqual, StateClosed)
+	})
+
+	Convey("A Breaker can trip on the failure rate of recent calls", t, func() {
+		b := &Breaker{FailureRate: 0.5, Window: 4, Clock: cm.New(time.Now())}
+
+		for _, op := range []func() error{fail, succeed, succeed} {
+			b.Do(background, op) //nolint:errcheck
+		}
+
+		So(b.State(), ShouldEqual, StateClosed)
+
+		b.Do(background, fail) //nolint:errcheck
+		So(b.State(), ShouldEqual, StateOpen)
+		So(b.CoolDown.Min, ShouldEqual, defaultCoolDownMin)
+
+		Convey("only considering the last Window calls", func() {
+			b = &Breaker{FailureRate: 0.5, Window: 4}
+
+			for _, op := range []func() error{fail, succeed, succeed, succeed, succeed, fail} {
+				b.Do(background, op) //nolint:errcheck
+			}
+
+			So(b.State(), ShouldEqual, StateClosed)
+		})
+	})
+
+	Convey("A Breaker without trip conditions never opens", t, func() {
+		b := &Breaker{}
+
+		for range 10 {
+			So(b.Do(background, fail), ShouldEqual, errFail)
+		}
+
+		So(b.State(), ShouldEqual, StateClosed)
+	})
+
+	Convey("A Breaker can be shared between goroutines", t, func() {
+		b := &Breaker{ConsecutiveFailures: 5, Clock: cm.New(time.Now())}
+
+		var wg sync.WaitGroup
+
+		for range 20 {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				b.Do(background, fail) //nolint:errcheck
+			}()
+		}
+
+		wg.Wait()
+		So(b.State(), ShouldEqual, StateOpen)
+	})
+}
+
+func TestUntil(t *testing.T) {
+	background := context.Background()
+
+	Convey("Until stops retries once its Breaker opens", t, func() {
+		var _ retry.Until = (*Until)(nil)
+
+		b := &Breaker{ConsecutiveFailures: 2, Clock: cm.New(time.Now())}
+		bo := &backoff.Backoff{Min: time.Millisecond, Max: time.Millisecond, Factor: 1, Sleeper: &bm.Sleeper{}}
+		calls := 0
+		op := func() error {
+			calls++
+
+			return errFail
+		}
+
+		status := retry.Do(background, b.Wrap(background, op),
+			retry.Untils{&Until{Breaker: b}, &retry.UntilLimit{Max: 5}}, bo, "doing foo")
+		So(status.StoppedBecause, ShouldEqual, retry.BecauseCircuitOpen)
+		So(status.Retried, ShouldEqual, 1)
+		So(calls, ShouldEqual, 2)
+
+		Convey("and stops them straight away if it is already open", func() {
+			status = retry.Do(background, b.Wrap(background, op),
+				retry.Untils{&Until{Breaker: b}, &retry.UntilLimit{Max: 5}}, bo, "doing foo")
+			So(status.StoppedBecause, ShouldEqual, retry.BecauseCircuitOpen)
+			So(status.Retried, ShouldEqual, 0)
+			So(status.Err, ShouldEqual, ErrOpen)
+			So(calls, ShouldEqual, 2)
+		})
+	})
+}
+
+// allowErr returns the error from the given Breaker's Allow(), ignoring the
+// Generation.
+func allowErr(b *Breaker) error {
+	_, err := b.Allow(context.Background())
+
+	return err
+}
@@ -0,0 +1,128 @@
+/*******************************************************************************
+ * Copyright (c) 2026 Genome Research Ltd.
+ *
+ * Author: Sendu Bala <sb10@sanger.ac.uk>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining
+ * a copy of this software and associated documentation files (the
+ * "Software"), to deal in the Software without restriction, including
+ * without limitation the rights to use, copy, modify, merge, publish,
+ * distribute, sublicense, and/or sell copies of the Software, and to
+ * permit persons to whom the Software is furnished to do so, subject to
+ * the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included
+ * in all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ ******************************************************************************/
+
+// package clock provides an abstraction of time, so that code that waits can be
+// driven by a manually advanced clock in tests (see the mock sub-package).
+package clock
+
+import (
+	"context"
+	"time"
+)
+
+// Timer is like a time.Timer.
+type Timer interface {
+	// C returns the channel that the current time is sent on when the Timer
+	// fires.
+	C() <-chan time.Time
+
+	// Stop prevents the Timer from firing, returning false if it had already
+	// fired or been stopped.
+	Stop() bool
+
+	// Reset changes the Timer to fire after the given duration, returning
+	// true if it had been active.
+	Reset(d time.Duration) bool
+}
+
+// Clock tells the time and lets you wait for time to pass.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// After waits for the given duration to elapse and then sends the current
+	// time on the returned channel.
+	After(d time.Duration) <-chan time.Time
+
+	// NewTimer returns a Timer that sends the current time on its channel
+	// after at least the given duration.
+	NewTimer(d time.Duration) Timer
+
+	// Sleep pauses the current goroutine for at least the given duration.
+	Sleep(d time.Duration)
+}
+
+// Real is a Clock that uses the real time, via the time package.
+type Real struct{}
+
+// Now returns time.Now().
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+// After returns time.After(d).
+func (Real) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// NewTimer returns a Timer that wraps time.NewTimer(d).
+func (Real) NewTimer(d time.Duration) Timer {
+	return &realTimer{timer: time.NewTimer(d)}
+}
+
+// Sleep calls time.Sleep(d).
+func (Real) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+// realTimer implements Timer by wrapping a time.Timer.
+type realTimer struct {
+	timer *time.Timer
+}
+
+// C returns the time.Timer's channel.
+func (t *realTimer) C() <-chan time.Time {
+	return t.timer.C
+}
+
+// Stop stops the time.Timer.
+func (t *realTimer) Stop() bool {
+	return t.timer.Stop()
+}
+
+// Reset resets the time.Timer.
+func (t *realTimer) Reset(d time.Duration) bool {
+	return t.timer.Reset(d)
+}
+
+type contextKey int
+
+const clockKey contextKey = 0
+
+// ContextWithClock returns a context that knows the given Clock, for use by
+// code that gets its Clock with FromContext().
+func ContextWithClock(ctx context.Context, c Clock) context.Context {
+	return context.WithValue(ctx, clockKey, c)
+}
+
+// FromContext returns the Clock set on the context with ContextWithClock(), or
+// a Real Clock if none was set.
+func FromContext(ctx context.Context) Clock {
+	if c, ok := ctx.Value(clockKey).(Clock); ok {
+		return c
+	}
+
+	return Real{}
+}
@@ -0,0 +1,67 @@
+/*******************************************************************************
+ * Copyright (c) 2026 Genome Research Ltd.
+ *
+ * Author: Sendu Bala <sb10@sanger.ac.uk>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining
+ * a copy of this software and associated documentation files (the
+ * "Software"), to deal in the Software without restriction, including
+ * without limitation the rights to use, copy, modify, merge, publish,
+ * distribute, sublicense, and/or sell copies of the Software, and to
+ * permit persons to whom the Software is furnished to do so, subject to
+ * the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included
+ * in all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ ******************************************************************************/
+
+package clock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClock(t *testing.T) {
+	delay := 5 * time.Millisecond
+
+	Convey("Real implements Clock using real time", t, func() {
+		var c Clock = Real{}
+
+		So(c.Now(), ShouldHappenWithin, time.Second, time.Now())
+
+		start := time.Now()
+		c.Sleep(delay)
+		So(time.Since(start), ShouldBeGreaterThanOrEqualTo, delay)
+
+		start = time.Now()
+		<-c.After(delay)
+		So(time.Since(start), ShouldBeGreaterThanOrEqualTo, delay)
+
+		timer := c.NewTimer(delay)
+		<-timer.C()
+		So(time.Since(start), ShouldBeGreaterThanOrEqualTo, 2*delay)
+		So(timer.Stop(), ShouldBeFalse)
+		So(timer.Reset(time.Hour), ShouldBeFalse)
+		So(timer.Stop(), ShouldBeTrue)
+	})
+
+	Convey("FromContext returns the Clock set on a context, defaulting to Real", t, func() {
+		ctx := context.Background()
+		So(FromContext(ctx), ShouldResemble, Real{})
+
+		c := &struct{ Real }{}
+		So(FromContext(ContextWithClock(ctx, c)), ShouldEqual, c)
+	})
+}
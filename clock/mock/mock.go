@@ -0,0 +1,214 @@
+/*******************************************************************************
+ * Copyright (c) 2026 Genome Research Ltd.
+ *
+ * Author: Sendu Bala <sb10@sanger.ac.uk>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining
+ * a copy of this software and associated documentation files (the
+ * "Software"), to deal in the Software without restriction, including
+ * without limitation the rights to use, copy, modify, merge, publish,
+ * distribute, sublicense, and/or sell copies of the Software, and to
+ * permit persons to whom the Software is furnished to do so, subject to
+ * the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included
+ * in all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ ******************************************************************************/
+
+// package mock contains a manually advanced implementation of clock.Clock.
+package mock
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/wtsi-ssg/wr/clock"
+)
+
+// Clock is an implementation of clock.Clock where time only passes when you
+// call Advance() or Set(). It is safe for concurrent use.
+type Clock struct {
+	mu      sync.Mutex
+	now     time.Time
+	timers  []*Timer
+	changed chan struct{}
+}
+
+// New returns a Clock with the given current time.
+func New(now time.Time) *Clock {
+	return &Clock{now: now, changed: make(chan struct{})}
+}
+
+// Now returns our current time.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// After returns the channel of a new Timer for the given duration.
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C()
+}
+
+// NewTimer returns a Timer that fires when our time has been advanced by at
+// least the given duration. A duration of 0 or less fires immediately.
+func (c *Clock) NewTimer(d time.Duration) clock.Timer {
+	t := &Timer{clock: c, ch: make(chan time.Time, 1)}
+	t.Reset(d)
+
+	return t
+}
+
+// Sleep blocks until our time has been advanced by at least the given
+// duration.
+func (c *Clock) Sleep(d time.Duration) {
+	<-c.After(d)
+}
+
+// Advance moves our time forward by the given duration, firing any Timers that
+// are due, in the order they are due.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.setTime(c.now.Add(d))
+}
+
+// Set sets our time to the given time, firing any Timers that are due, in the
+// order they are due. Setting an earlier time fires nothing.
+func (c *Clock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.setTime(now)
+}
+
+// setTime sets our time and fires due Timers. You must hold the lock.
+func (c *Clock) setTime(now time.Time) {
+	c.now = now
+
+	sort.SliceStable(c.timers, func(i, j int) bool {
+		return c.timers[i].deadline.Before(c.timers[j].deadline)
+	})
+
+	for len(c.timers) > 0 && !c.timers[0].deadline.After(now) {
+		c.timers[0].fire(now)
+		c.timers = c.timers[1:]
+	}
+
+	c.notifyChanged()
+}
+
+// Waiters returns the number of Timers (including those used by After() and
+// Sleep()) that are waiting to fire.
+func (c *Clock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.timers)
+}
+
+// BlockUntil blocks until at least the given number of Timers are waiting to
+// fire. Use this before calling Advance() to make sure that the code you are
+// testing has started waiting.
+func (c *Clock) BlockUntil(waiters int) {
+	for {
+		c.mu.Lock()
+		n, changed := len(c.timers), c.changed
+		c.mu.Unlock()
+
+		if n >= waiters {
+			return
+		}
+
+		<-changed
+	}
+}
+
+// notifyChanged wakes up BlockUntil() calls. You must hold the lock.
+func (c *Clock) notifyChanged() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// add adds the given Timer to those waiting, or fires it straight away if it
+// is already due. You must hold the lock.
+func (c *Clock) add(t *Timer) {
+	if !t.deadline.After(c.now) {
+		t.fire(c.now)
+
+		return
+	}
+
+	c.timers = append(c.timers, t)
+	c.notifyChanged()
+}
+
+// remove removes the given Timer from those waiting, returning true if it was
+// waiting. You must hold the lock.
+func (c *Clock) remove(t *Timer) bool {
+	for i, waiting := range c.timers {
+		if waiting == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			c.notifyChanged()
+
+			return true
+		}
+	}
+
+	return false
+}
+
+// Timer is an implementation of clock.Timer returned by Clock.NewTimer().
+type Timer struct {
+	clock    *Clock
+	ch       chan time.Time
+	deadline time.Time
+}
+
+// C returns the channel that our Clock's time is sent on when we fire.
+func (t *Timer) C() <-chan time.Time {
+	return t.ch
+}
+
+// Stop stops us from firing, returning false if we had already fired or been
+// stopped.
+func (t *Timer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	return t.clock.remove(t)
+}
+
+// Reset makes us fire when our Clock has been advanced by at least the given
+// duration from its current time, returning true if we were waiting to fire.
+func (t *Timer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	wasWaiting := t.clock.remove(t)
+	t.deadline = t.clock.now.Add(d)
+	t.clock.add(t)
+
+	return wasWaiting
+}
+
+// fire sends the given time on our channel, unless a previous time is still
+// unreceived.
+func (t *Timer) fire(now time.Time) {
+	select {
+	case t.ch <- now:
+	default:
+	}
+}
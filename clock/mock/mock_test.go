@@ -0,0 +1,126 @@
+/*******************************************************************************
+ * Copyright (c) 2026 Genome Research Ltd.
+ *
+ * Author: Sendu Bala <sb10@sanger.ac.uk>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining
+ * a copy of this software and associated documentation files (the
+ * "Software"), to deal in the Software without restriction, including
+ * without limitation the rights to use, copy, modify, merge, publish,
+ * distribute, sublicense, and/or sell copies of the Software, and to
+ * permit persons to whom the Software is furnished to do so, subject to
+ * the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included
+ * in all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ ******************************************************************************/
+
+package mock
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/wtsi-ssg/wr/clock"
+)
+
+// fired returns the time received from the given channel, or the zero time if
+// nothing has been sent.
+func fired(ch <-chan time.Time) time.Time {
+	select {
+	case t := <-ch:
+		return t
+	default:
+		return time.Time{}
+	}
+}
+
+func TestClock(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	Convey("Given a mock Clock", t, func() {
+		var _ clock.Clock = (*Clock)(nil)
+
+		c := New(start)
+		So(c.Now(), ShouldEqual, start)
+
+		Convey("Time only passes when advanced", func() {
+			c.Advance(time.Hour)
+			So(c.Now(), ShouldEqual, start.Add(time.Hour))
+
+			c.Set(start)
+			So(c.Now(), ShouldEqual, start)
+		})
+
+		Convey("Timers fire when time is advanced past them", func() {
+			t1 := c.NewTimer(time.Hour)
+			after := c.After(time.Minute)
+			So(c.Waiters(), ShouldEqual, 2)
+
+			c.Advance(30 * time.Second)
+			So(fired(after), ShouldBeZeroValue)
+
+			c.Advance(30 * time.Second)
+			So(fired(after), ShouldEqual, start.Add(time.Minute))
+			So(fired(t1.C()), ShouldBeZeroValue)
+			So(c.Waiters(), ShouldEqual, 1)
+
+			c.Set(start.Add(2 * time.Hour))
+			So(fired(t1.C()), ShouldEqual, start.Add(2*time.Hour))
+			So(c.Waiters(), ShouldEqual, 0)
+			So(t1.Stop(), ShouldBeFalse)
+
+			Convey("and can be reset", func() {
+				So(t1.Reset(time.Minute), ShouldBeFalse)
+				So(t1.Reset(time.Hour), ShouldBeTrue)
+				c.Advance(time.Minute)
+				So(fired(t1.C()), ShouldBeZeroValue)
+				c.Advance(time.Hour)
+				So(fired(t1.C()), ShouldNotBeZeroValue)
+			})
+		})
+
+		Convey("Stopped Timers don't fire", func() {
+			t1 := c.NewTimer(time.Minute)
+			So(t1.Stop(), ShouldBeTrue)
+			So(t1.Stop(), ShouldBeFalse)
+			c.Advance(time.Hour)
+			So(fired(t1.C()), ShouldBeZeroValue)
+		})
+
+		Convey("Timers for 0 or less fire immediately", func() {
+			So(fired(c.After(0)), ShouldEqual, start)
+			So(fired(c.After(-time.Second)), ShouldEqual, start)
+		})
+
+		Convey("Sleep() waits until time is advanced, which you can wait for", func() {
+			done := make(chan bool)
+
+			go func() {
+				c.Sleep(time.Hour)
+				done <- true
+			}()
+
+			c.BlockUntil(1)
+			c.Advance(59 * time.Minute)
+
+			select {
+			case <-done:
+				So(false, ShouldBeTrue)
+			case <-time.After(10 * time.Millisecond):
+			}
+
+			c.Advance(time.Minute)
+			So(<-done, ShouldBeTrue)
+		})
+	})
+}
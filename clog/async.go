@@ -0,0 +1,371 @@
+/*******************************************************************************
+ * Copyright (c) 2026 Genome Research Ltd.
+ *
+ * Author: Sendu Bala <sb10@sanger.ac.uk>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining
+ * a copy of this software and associated documentation files (the
+ * "Software"), to deal in the Software without restriction, including
+ * without limitation the rights to use, copy, modify, merge, publish,
+ * distribute, sublicense, and/or sell copies of the Software, and to
+ * permit persons to whom the Software is furnished to do so, subject to
+ * the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included
+ * in all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ ******************************************************************************/
+
+package clog
+
+// this file implements a handler that logs asynchronously.
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-stack/stack"
+	log "github.com/inconshreveable/log15"
+)
+
+// OverflowPolicy determines what an AsyncHandler does with a new record when
+// its queue is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock makes logging wait until there is space in the queue.
+	OverflowBlock OverflowPolicy = iota
+
+	// OverflowDropOldest discards the oldest queued record to make space.
+	OverflowDropOldest
+
+	// OverflowDropBelow discards the new record if it is less severe than
+	// AsyncOptions.DropBelow, and otherwise waits as for OverflowBlock.
+	OverflowDropBelow
+)
+
+const (
+	// DefaultAsyncQueueSize is the queue size used by NewAsyncHandler() when
+	// AsyncOptions.QueueSize is not set.
+	DefaultAsyncQueueSize = 1024
+
+	// DefaultAsyncReportInterval is the interval used by NewAsyncHandler()
+	// when AsyncOptions.ReportInterval is not set.
+	DefaultAsyncReportInterval = time.Minute
+)
+
+// AsyncOptions configures NewAsyncHandler().
+type AsyncOptions struct {
+	// QueueSize is the number of records that can be waiting to be written
+	// before Overflow applies. Defaults to DefaultAsyncQueueSize.
+	QueueSize int
+
+	// Overflow is what to do with records when the queue is full. Defaults to
+	// OverflowBlock.
+	Overflow OverflowPolicy
+
+	// DropBelow is the level (valid names are as for ToDefaultAtLevel())
+	// that records must be at least as severe as to not be dropped when using
+	// OverflowDropBelow.
+	DropBelow string
+
+	// ReportInterval is how often a warning with the number of records
+	// dropped since the last warning is logged, if any were dropped. Defaults
+	// to DefaultAsyncReportInterval.
+	ReportInterval time.Duration
+}
+
+// queueSize returns our QueueSize, or the default.
+func (o AsyncOptions) queueSize() int {
+	if o.QueueSize > 0 {
+		return o.QueueSize
+	}
+
+	return DefaultAsyncQueueSize
+}
+
+// reportInterval returns our ReportInterval, or the default.
+func (o AsyncOptions) reportInterval() time.Duration {
+	if o.ReportInterval > 0 {
+		return o.ReportInterval
+	}
+
+	return DefaultAsyncReportInterval
+}
+
+// flushWaiter is used by Flush() to wait for a number of queued records to be
+// dealt with.
+type flushWaiter struct {
+	target uint64
+	done   chan struct{}
+}
+
+// AsyncHandler is a log15 handler that queues records and passes them to
+// another handler in the background, so that logging isn't slowed down by a
+// slow handler. Make one with NewAsyncHandler(). It is safe for concurrent
+// use.
+type AsyncHandler struct {
+	handler    log.Handler
+	overflow   OverflowPolicy
+	dropBelow  log.Lvl
+	queue      chan *log.Record
+	stop       chan struct{}
+	stopped    chan struct{}
+	unregister func()
+	enqueueMu  sync.Mutex
+	closed     bool
+	enqueued   atomic.Uint64
+	totalDrops atomic.Uint64
+	mu         sync.Mutex
+	completed  uint64
+	drops      int
+	waiters    []*flushWaiter
+}
+
+// NewAsyncHandler returns an AsyncHandler that passes records to the given
+// handler in the background, in the order they were logged.
+//
+// The returned handler is registered with AddFlusher(), so that queued records
+// are written before Fatal() exits. It can be used as the root handler (eg.
+// with ToHandlerAtLevel()), or with ContextWithLogHandler() to log to a per-job
+// handler asynchronously. Call Close() when you're done with it.
+func NewAsyncHandler(h log.Handler, opts AsyncOptions) *AsyncHandler {
+	a := &AsyncHandler{
+		handler:   h,
+		overflow:  opts.Overflow,
+		dropBelow: lvlFromString(opts.DropBelow),
+		queue:     make(chan *log.Record, opts.queueSize()),
+		stop:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+
+	a.unregister = AddFlusher(a)
+
+	go a.run(opts.reportInterval())
+
+	return a
+}
+
+// Log implements log15.Handler, queuing the record to be passed on. If we have
+// been closed, the record is instead passed on straight away.
+//
+// The call stack of a queued record is captured now, so that a
+// CallerInfoHandler() we pass it to can still add caller information.
+func (a *AsyncHandler) Log(r *log.Record) error {
+	a.enqueueMu.Lock()
+
+	if a.closed {
+		a.enqueueMu.Unlock()
+
+		return a.handler.Log(r)
+	}
+
+	defer a.enqueueMu.Unlock()
+
+	holdStack(r)
+
+	if a.enqueue(r) {
+		a.enqueued.Add(1)
+	} else {
+		releaseStack(r)
+		a.dropped(1)
+	}
+
+	return nil
+}
+
+// enqueue adds the given record to our queue, applying our overflow policy if
+// it is full. Returns false if the record was dropped. Must be called while
+// holding enqueueMu.
+func (a *AsyncHandler) enqueue(r *log.Record) bool {
+	select {
+	case a.queue <- r:
+		return true
+	default:
+	}
+
+	switch {
+	case a.overflow == OverflowDropOldest:
+		a.dropOldest()
+	case a.overflow == OverflowDropBelow && r.Lvl > a.dropBelow:
+		return false
+	}
+
+	a.queue <- r
+
+	return true
+}
+
+// dropOldest discards the oldest record in our queue, if any. Because only
+// our background goroutine otherwise receives from the queue, and we hold
+// enqueueMu, there will be space in the queue afterwards.
+func (a *AsyncHandler) dropOldest() {
+	select {
+	case r := <-a.queue:
+		releaseStack(r)
+		a.dropped(1)
+		a.complete()
+	default:
+	}
+}
+
+// dropped notes that the given number of records were dropped.
+func (a *AsyncHandler) dropped(n int) {
+	a.totalDrops.Add(uint64(n))
+
+	a.mu.Lock()
+	a.drops += n
+	a.mu.Unlock()
+}
+
+// complete notes that a queued record has been dealt with, releasing any
+// Flush() calls that were waiting for it.
+func (a *AsyncHandler) complete() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.completed++
+
+	remaining := a.waiters[:0]
+
+	for _, w := range a.waiters {
+		if a.completed >= w.target {
+			close(w.done)
+
+			continue
+		}
+
+		remaining = append(remaining, w)
+	}
+
+	a.waiters = remaining
+}
+
+// run passes on queued records and periodically reports drops, until Close()
+// is called.
+func (a *AsyncHandler) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case r := <-a.queue:
+			a.write(r)
+		case <-ticker.C:
+			a.reportDrops()
+		case <-a.stop:
+			a.drain()
+			a.reportDrops()
+			close(a.stopped)
+
+			return
+		}
+	}
+}
+
+// write passes the given record on to our handler.
+func (a *AsyncHandler) write(r *log.Record) {
+	a.handler.Log(r) //nolint:errcheck
+	releaseStack(r)
+	a.complete()
+}
+
+// drain passes on any records remaining in our queue.
+func (a *AsyncHandler) drain() {
+	for {
+		select {
+		case r := <-a.queue:
+			a.write(r)
+		default:
+			return
+		}
+	}
+}
+
+// reportDrops passes on a warning saying how many records were dropped since
+// the last warning, if any were.
+func (a *AsyncHandler) reportDrops() {
+	a.mu.Lock()
+	drops := a.drops
+	a.drops = 0
+	a.mu.Unlock()
+
+	if drops == 0 {
+		return
+	}
+
+	a.handler.Log(&log.Record{ //nolint:errcheck
+		Time:     time.Now(),
+		Lvl:      log.LvlWarn,
+		Msg:      "log records dropped by async handler",
+		Ctx:      []interface{}{DroppedKey, drops},
+		Call:     stack.Caller(0),
+		KeyNames: log.RecordKeyNames{Time: timeKey, Msg: msgKey, Lvl: lvlKey},
+	})
+}
+
+// Dropped returns the total number of records that have been dropped due to
+// our overflow policy.
+func (a *AsyncHandler) Dropped() uint64 {
+	return a.totalDrops.Load()
+}
+
+// Flush implements Flusher, waiting until all records logged before this call
+// have been passed on (or dropped), and then reporting any drops. Returns the
+// context's error if it is done first.
+func (a *AsyncHandler) Flush(ctx context.Context) error {
+	select {
+	case <-a.waitFor(a.enqueued.Load()):
+		a.reportDrops()
+
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// waitFor returns a channel that is closed once the given number of queued
+// records have been dealt with.
+func (a *AsyncHandler) waitFor(target uint64) <-chan struct{} {
+	w := &flushWaiter{target: target, done: make(chan struct{})}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.completed >= target {
+		close(w.done)
+	} else {
+		a.waiters = append(a.waiters, w)
+	}
+
+	return w.done
+}
+
+// Close passes on all queued records, stops our background goroutine and
+// unregisters us from Fatal(). Records logged afterwards are passed on
+// synchronously. It is safe to call more than once.
+func (a *AsyncHandler) Close() {
+	a.enqueueMu.Lock()
+
+	if a.closed {
+		a.enqueueMu.Unlock()
+
+		return
+	}
+
+	a.closed = true
+	close(a.stop)
+	a.enqueueMu.Unlock()
+
+	<-a.stopped
+	a.unregister()
+}
@@ -0,0 +1,252 @@
+/*******************************************************************************
+ * Copyright (c) 2026 Genome Research Ltd.
+ *
+ * Author: Sendu Bala <sb10@sanger.ac.uk>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining
+ * a copy of this software and associated documentation files (the
+ * "Software"), to deal in the Software without restriction, including
+ * without limitation the rights to use, copy, modify, merge, publish,
+ * distribute, sublicense, and/or sell copies of the Software, and to
+ * permit persons to whom the Software is furnished to do so, subject to
+ * the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included
+ * in all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ ******************************************************************************/
+
+package clog
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	log "github.com/inconshreveable/log15"
+	. "github.com/smartystreets/goconvey/convey"
+	fl "github.com/wtsi-ssg/wr/fs/file"
+	ft "github.com/wtsi-ssg/wr/fs/test"
+)
+
+// gatedHandler is a slow handler that doesn't pass records on to its handler
+// until its gate is closed.
+type gatedHandler struct {
+	handler log.Handler
+	gate    chan struct{}
+	entered chan struct{}
+	once    sync.Once
+}
+
+// newGatedHandler returns a gatedHandler that passes records on to the given
+// handler once open() is called.
+func newGatedHandler(h log.Handler) *gatedHandler {
+	return &gatedHandler{handler: h, gate: make(chan struct{}), entered: make(chan struct{}, 100)}
+}
+
+// Log notes that it was entered, then waits for the gate to be opened before
+// passing the record on.
+func (g *gatedHandler) Log(r *log.Record) error {
+	g.entered <- struct{}{}
+	<-g.gate
+
+	return g.handler.Log(r)
+}
+
+// open lets records through. It is safe to call more than once.
+func (g *gatedHandler) open() {
+	g.once.Do(func() { close(g.gate) })
+}
+
+// closeAsync opens the given gatedHandler and closes the given AsyncHandler.
+func closeAsync(gated *gatedHandler, async *AsyncHandler) {
+	gated.open()
+	async.Close()
+}
+
+// logReturned calls the given logging function in the background, returning a
+// channel that is closed when it returns.
+func logReturned(fn func()) chan struct{} {
+	returned := make(chan struct{})
+
+	go func() {
+		fn()
+		close(returned)
+	}()
+
+	return returned
+}
+
+// hasClosed returns true if the given channel is closed within a short time.
+func hasClosed(ch chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	case <-time.After(50 * time.Millisecond):
+		return false
+	}
+}
+
+func TestAsync(t *testing.T) {
+	background := context.Background()
+
+	Convey("Given an AsyncHandler in front of a slow handler", t, func() {
+		ring := NewRingHandler(100)
+		gated := newGatedHandler(ring)
+		async := NewAsyncHandler(gated, AsyncOptions{QueueSize: 1, Overflow: OverflowBlock})
+		defer closeAsync(gated, async)
+
+		ToHandlerAtLevel(async, "debug")
+		defer ToDefault()
+
+		Warn(background, "a")
+		<-gated.entered
+		Warn(background, "b")
+
+		Convey("logging doesn't wait for records to be written", func() {
+			So(ring.Len(), ShouldEqual, 0)
+
+			Convey("and Flush() waits for them to be written", func() {
+				ctx, cancel := context.WithTimeout(background, 10*time.Millisecond)
+				defer cancel()
+				So(errors.Is(async.Flush(ctx), context.DeadlineExceeded), ShouldBeTrue)
+
+				gated.open()
+				So(async.Flush(background), ShouldBeNil)
+				So(msgsOf(ring.Records(nil)), ShouldResemble, []string{"a", "b"})
+			})
+		})
+
+		Convey("logging blocks when the queue is full", func() {
+			returned := logReturned(func() { Warn(background, "c") })
+			So(hasClosed(returned), ShouldBeFalse)
+
+			gated.open()
+			So(hasClosed(returned), ShouldBeTrue)
+			So(async.Flush(background), ShouldBeNil)
+			So(msgsOf(ring.Records(nil)), ShouldResemble, []string{"a", "b", "c"})
+			So(async.Dropped(), ShouldEqual, 0)
+		})
+
+		Convey("After Close(), queued records are written and logging is synchronous", func() {
+			gated.open()
+			async.Close()
+			So(msgsOf(ring.Records(nil)), ShouldResemble, []string{"a", "b"})
+
+			Warn(background, "c")
+			So(msgsOf(ring.Records(nil)), ShouldResemble, []string{"a", "b", "c"})
+			async.Close()
+		})
+	})
+
+	Convey("An AsyncHandler can drop the oldest records when full", t, func() {
+		ring := NewRingHandler(100)
+		gated := newGatedHandler(ring)
+		async := NewAsyncHandler(gated, AsyncOptions{QueueSize: 2, Overflow: OverflowDropOldest})
+		defer closeAsync(gated, async)
+
+		l := log.New()
+		l.SetHandler(async)
+		l.Info("a")
+		<-gated.entered
+
+		for _, msg := range []string{"b", "c", "d", "e"} {
+			l.Info(msg)
+		}
+
+		gated.open()
+		So(async.Flush(background), ShouldBeNil)
+
+		records := ring.Records(nil)
+		So(msgsOf(records), ShouldResemble, []string{"a", "d", "e", "log records dropped by async handler"})
+		So(records[3].Lvl, ShouldEqual, log.LvlWarn)
+		So(records[3].HasField(DroppedKey, 2), ShouldBeTrue)
+		So(async.Dropped(), ShouldEqual, 2)
+	})
+
+	Convey("An AsyncHandler can drop less severe records when full, periodically reporting drops", t, func() {
+		ring := NewRingHandler(100)
+		gated := newGatedHandler(ring)
+		async := NewAsyncHandler(gated, AsyncOptions{
+			QueueSize:      1,
+			Overflow:       OverflowDropBelow,
+			DropBelow:      "warn",
+			ReportInterval: 10 * time.Millisecond,
+		})
+		defer closeAsync(gated, async)
+
+		l := log.New()
+		l.SetHandler(async)
+		l.Info("a")
+		<-gated.entered
+		l.Info("b")
+		l.Info("c")
+		l.Debug("d")
+		So(async.Dropped(), ShouldEqual, 2)
+
+		returned := logReturned(func() { l.Error("e") })
+		So(hasClosed(returned), ShouldBeFalse)
+
+		gated.open()
+		So(hasClosed(returned), ShouldBeTrue)
+		So(waitForRecords(ring, 4, time.Second), ShouldBeTrue)
+
+		records := ring.Records(&Query{Fields: map[string]interface{}{DroppedKey: 2}})
+		So(msgsOf(records), ShouldResemble, []string{"log records dropped by async handler"})
+
+		msgs := msgsOf(ring.Records(nil))
+		for _, msg := range []string{"a", "b", "e"} {
+			So(msgs, ShouldContain, msg)
+		}
+	})
+
+	Convey("A per-job AsyncHandler is flushed by Fatal()", t, func() {
+		os.Setenv("WR_FATAL_EXIT_TEST", "1")
+		defer os.Unsetenv("WR_FATAL_EXIT_TEST")
+
+		ring := NewRingHandler(100)
+		gated := newGatedHandler(ring)
+		async := NewAsyncHandler(gated, AsyncOptions{})
+		defer closeAsync(gated, async)
+
+		ctx := ContextWithLogHandler(ContextWithJobKey(background, "job1"), async)
+		Info(ctx, "a")
+		<-gated.entered
+
+		go func() {
+			<-time.After(10 * time.Millisecond)
+			gated.open()
+		}()
+
+		Fatal(ctx, "b")
+
+		records := ring.Records(nil)
+		So(msgsOf(records), ShouldResemble, []string{"a", "b"})
+		So(records[1].HasField("jobkey", "job1"), ShouldBeTrue)
+	})
+
+	Convey("An AsyncHandler in front of a file handler keeps caller info", t, func() {
+		logPath := ft.FilePathInTempDir(t, "clog.log")
+		fh, err := CreateFileHandlerAtLevel(logPath, "debug")
+		So(err, ShouldBeNil)
+
+		async := NewAsyncHandler(fh, AsyncOptions{})
+		ctx := ContextWithLogHandler(background, async)
+		Warn(ctx, "a")
+		async.Close()
+
+		content, err := fl.ToString(logPath)
+		So(err, ShouldBeNil)
+		So(content, ShouldContainSubstring, "caller=clog/async_test.go")
+	})
+}
@@ -0,0 +1,251 @@
+/*******************************************************************************
+ * Copyright (c) 2026 Genome Research Ltd.
+ *
+ * Author: Sendu Bala <sb10@sanger.ac.uk>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining
+ * a copy of this software and associated documentation files (the
+ * "Software"), to deal in the Software without restriction, including
+ * without limitation the rights to use, copy, modify, merge, publish,
+ * distribute, sublicense, and/or sell copies of the Software, and to
+ * permit persons to whom the Software is furnished to do so, subject to
+ * the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included
+ * in all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ ******************************************************************************/
+
+package clog
+
+// this file implements adding caller information to log records.
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/go-stack/stack"
+	log "github.com/inconshreveable/log15"
+)
+
+// CallerDetail is the kind of caller information that CallerInfoHandler() adds
+// to records.
+type CallerDetail int
+
+const (
+	// CallerNone adds no caller information.
+	CallerNone CallerDetail = iota
+
+	// CallerFile adds the file and line number of the calling function to the
+	// context with key "caller".
+	CallerFile
+
+	// CallerFunc adds what CallerFile does, plus the name of the calling
+	// function with key "func".
+	CallerFunc
+
+	// CallerStack adds a stack trace to the context with key "stack".
+	CallerStack
+)
+
+const (
+	funcKey  = "func"
+	stackKey = "stack"
+)
+
+// queuedStacks holds the call stacks of records that an AsyncHandler has
+// queued, keyed by record, since by the time CallerInfoHandler() sees them
+// the logging call is no longer on the stack.
+var queuedStacks sync.Map //nolint:gochecknoglobals
+
+// holdStack stores the current call stack of the given record, for
+// CallerInfoHandler() to use if it sees the record on another goroutine.
+func holdStack(r *log.Record) {
+	queuedStacks.Store(r, recordStack(r))
+}
+
+// releaseStack forgets any call stack stored for the given record.
+func releaseStack(r *log.Record) {
+	queuedStacks.Delete(r)
+}
+
+// recordStack returns the call stack of the given record, starting at the log
+// call, preferring one stored by holdStack().
+func recordStack(r *log.Record) stack.CallStack {
+	if s, ok := queuedStacks.Load(r); ok {
+		return s.(stack.CallStack) //nolint:forcetypeassert
+	}
+
+	return stack.Trace().TrimBelow(r.Call).TrimRuntime()
+}
+
+// callerConfig is configured by CallerOptions.
+type callerConfig struct {
+	details  map[log.Lvl]CallerDetail
+	skip     int
+	prefixes []string
+}
+
+// newCallerConfig returns a callerConfig with our default details, modified by
+// the given options.
+func newCallerConfig(opts []CallerOption) *callerConfig {
+	c := &callerConfig{details: map[log.Lvl]CallerDetail{
+		log.LvlDebug: CallerFile,
+		log.LvlInfo:  CallerNone,
+		log.LvlWarn:  CallerFile,
+		log.LvlError: CallerFile,
+		log.LvlCrit:  CallerStack,
+	}}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// CallerOption configures CallerInfoHandler(), and the functions that use it.
+type CallerOption func(*callerConfig)
+
+// WithCallerDetail makes CallerInfoHandler() add the given detail to records at
+// the given level (valid names are as for ToDefaultAtLevel()).
+func WithCallerDetail(lvl string, detail CallerDetail) CallerOption {
+	return func(c *callerConfig) {
+		c.details[lvlFromString(lvl)] = detail
+	}
+}
+
+// WithCallerSkip makes CallerInfoHandler() skip the given number of frames
+// when finding the calling function, and when starting stack traces. Use this
+// when you log via your own helper functions that call clog's functions, so
+// that the caller of your helper is reported instead of the helper itself.
+func WithCallerSkip(frames int) CallerOption {
+	return func(c *callerConfig) {
+		c.skip = frames
+	}
+}
+
+// WithCallerTrimPrefixes makes CallerInfoHandler() report callers, function
+// names and stack traces using full import paths, with the first of the given
+// prefixes that matches removed. Eg. with a prefix of "github.com/wtsi-ssg/wr/"
+// a caller would be "clog/clog.go:42" and a function would be "clog.Warn".
+func WithCallerTrimPrefixes(prefixes ...string) CallerOption {
+	return func(c *callerConfig) {
+		c.prefixes = append(c.prefixes, prefixes...)
+	}
+}
+
+// CallerInfoHandler returns a Handler that adds information about the calling
+// function to the context of records before passing them to the given handler.
+//
+// By default, at the Debug, Warn and Error levels, it adds the file and line
+// number of the calling function to the context with key "caller". At the Crit
+// level it instead adds a stack trace to the context with key "stack". At the
+// Info level nothing is added. The stack trace is a slice of call sites, which
+// logfmt formats as a space separated list inside matching []'s and JSON
+// formats as an array. The most recent call site is listed first.
+//
+// The given options can change this; see WithCallerDetail(), WithCallerSkip()
+// and WithCallerTrimPrefixes().
+func CallerInfoHandler(h log.Handler, opts ...CallerOption) log.Handler {
+	c := newCallerConfig(opts)
+
+	return log.FuncHandler(func(r *log.Record) error {
+		detail := c.details[r.Lvl]
+		if detail == CallerNone {
+			return h.Log(r)
+		}
+
+		s := recordStack(r)
+		if len(s) > 0 {
+			r.Ctx = c.appendCallerInfo(r.Ctx, detail, s)
+		}
+
+		return h.Log(r)
+	})
+}
+
+// appendCallerInfo appends the given detail about the given non-empty stack,
+// which starts at the log call, to the given context.
+func (c *callerConfig) appendCallerInfo(ctx []interface{}, detail CallerDetail, s stack.CallStack) []interface{} {
+	switch detail {
+	case CallerFile:
+		return append(ctx, callerKey, c.callerPath(c.caller(s)))
+	case CallerFunc:
+		call := c.caller(s)
+
+		return append(ctx, callerKey, c.callerPath(call), funcKey, c.funcName(call))
+	case CallerStack:
+		return append(ctx, stackKey, c.callStackToStrings(s[c.clampedIndex(s, c.skip):]))
+	}
+
+	return ctx
+}
+
+// caller returns the call site of the calling function from the given stack,
+// which starts at a clog logging function.
+func (c *callerConfig) caller(s stack.CallStack) stack.Call {
+	return s[c.clampedIndex(s, 1+c.skip)]
+}
+
+// clampedIndex returns the given index, or the last index of the given stack if
+// it is too short.
+func (c *callerConfig) clampedIndex(s stack.CallStack, i int) int {
+	if i >= len(s) {
+		return len(s) - 1
+	}
+
+	return i
+}
+
+// callerPath returns the file and line number of the given call site. Without
+// prefixes this is relative to the call's package's parent directory.
+func (c *callerConfig) callerPath(call stack.Call) string {
+	if len(c.prefixes) == 0 {
+		return filepath.Join(fmt.Sprintf("%k", call), fmt.Sprintf("%v", call))
+	}
+
+	return c.trim(fmt.Sprintf("%+v", call))
+}
+
+// funcName returns the name of the function of the given call site. Without
+// prefixes this is qualified by the function's package name.
+func (c *callerConfig) funcName(call stack.Call) string {
+	if len(c.prefixes) == 0 {
+		return fmt.Sprintf("%k.%n", call, call)
+	}
+
+	return c.trim(fmt.Sprintf("%+n", call))
+}
+
+// callStackToStrings returns each call site in the given stack formatted with
+// its full path and line number, trimmed of our prefixes.
+func (c *callerConfig) callStackToStrings(s stack.CallStack) []string {
+	calls := make([]string, len(s))
+
+	for i, call := range s {
+		calls[i] = c.trim(fmt.Sprintf("%+v", call))
+	}
+
+	return calls
+}
+
+// trim removes the first of our prefixes that the given string starts with.
+func (c *callerConfig) trim(str string) string {
+	for _, prefix := range c.prefixes {
+		if strings.HasPrefix(str, prefix) {
+			return strings.TrimPrefix(str, prefix)
+		}
+	}
+
+	return str
+}
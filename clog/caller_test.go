@@ -0,0 +1,145 @@
+/*******************************************************************************
+ * Copyright (c) 2026 Genome Research Ltd.
+ *
+ * Author: Sendu Bala <sb10@sanger.ac.uk>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining
+ * a copy of this software and associated documentation files (the
+ * "Software"), to deal in the Software without restriction, including
+ * without limitation the rights to use, copy, modify, merge, publish,
+ * distribute, sublicense, and/or sell copies of the Software, and to
+ * permit persons to whom the Software is furnished to do so, subject to
+ * the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included
+ * in all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ ******************************************************************************/
+
+package clog
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	ft "github.com/wtsi-ssg/wr/fs/test"
+)
+
+// warnViaHelper is a helper function that logs using clog.
+func warnViaHelper(ctx context.Context) {
+	Warn(ctx, "helped")
+}
+
+// callerLine returns the file and line number of its caller, plus the given
+// offset, in the form used for callers by CallerInfoHandler().
+func callerLine(offset int) string {
+	_, _, line, _ := runtime.Caller(1) //nolint:dogsled
+
+	return fmt.Sprintf("clog/caller_test.go:%d", line+offset)
+}
+
+func TestCallerOptions(t *testing.T) {
+	background := context.Background()
+
+	Convey("You can choose the caller detail per level", t, func() {
+		ring := NewRingHandler(10)
+		ToHandlerAtLevel(ring, "debug",
+			WithCallerDetail("info", CallerFile),
+			WithCallerDetail("warn", CallerNone),
+			WithCallerDetail("error", CallerFunc),
+			WithCallerDetail("debug", CallerStack),
+		)
+		defer ToDefault()
+
+		Info(background, "info")
+		infoLine := callerLine(-1)
+		Warn(background, "warn")
+		Error(background, "error")
+		errorLine := callerLine(-1)
+		Debug(background, "debug")
+		Crit(background, "crit")
+
+		records := ring.Records(nil)
+		So(len(records), ShouldEqual, 5)
+		So(records[0].Caller, ShouldEqual, infoLine)
+		So(records[1].Ctx, ShouldBeEmpty)
+		So(records[2].Caller, ShouldEqual, errorLine)
+		So(records[2].Ctx[funcKey], ShouldStartWith, "clog.TestCallerOptions.")
+
+		stack, ok := records[3].Ctx[stackKey].([]string)
+		So(ok, ShouldBeTrue)
+		So(stack[0], ShouldStartWith, "github.com/wtsi-ssg/wr/clog/clog.go:")
+		So(stack[1], ShouldStartWith, "github.com/wtsi-ssg/wr/clog/caller_test.go:")
+		So(records[3].Caller, ShouldBeBlank)
+		So(records[4].Ctx, ShouldContainKey, stackKey)
+	})
+
+	Convey("You can skip frames so that helper functions report their caller", t, func() {
+		ring := NewRingHandler(10)
+		ToHandlerAtLevel(ring, "debug", WithCallerSkip(1))
+		defer ToDefault()
+
+		warnViaHelper(background)
+		helperCallLine := callerLine(-1)
+		Crit(background, "crit")
+
+		records := ring.Records(nil)
+		So(len(records), ShouldEqual, 2)
+		So(records[0].Caller, ShouldEqual, helperCallLine)
+
+		stack, ok := records[1].Ctx[stackKey].([]string)
+		So(ok, ShouldBeTrue)
+		So(stack[0], ShouldStartWith, "github.com/wtsi-ssg/wr/clog/caller_test.go:")
+
+		Convey("Skipping too many frames reports the outermost caller", func() {
+			ToHandlerAtLevel(ring, "debug", WithCallerSkip(1000))
+			Warn(background, "outer")
+			So(ring.Records(nil)[2].Caller, ShouldNotBeBlank)
+		})
+	})
+
+	Convey("You can trim prefixes from full caller paths", t, func() {
+		ring := NewRingHandler(10)
+		ToHandlerAtLevel(ring, "debug",
+			WithCallerTrimPrefixes("example.com/", "github.com/wtsi-ssg/"),
+			WithCallerDetail("warn", CallerFunc),
+		)
+		defer ToDefault()
+
+		Warn(background, "warn")
+		Crit(background, "crit")
+
+		records := ring.Records(nil)
+		So(records[0].Caller, ShouldStartWith, "wr/clog/caller_test.go:")
+		So(records[0].Ctx[funcKey], ShouldStartWith, "wr/clog.TestCallerOptions.")
+
+		stack, ok := records[1].Ctx[stackKey].([]string)
+		So(ok, ShouldBeTrue)
+		So(stack[0], ShouldStartWith, "wr/clog/clog.go:")
+	})
+
+	Convey("File handlers accept caller options", t, func() {
+		logPath := ft.FilePathInTempDir(t, "clog.log")
+		err := ToFileAtLevel(logPath, "info", WithCallerDetail("info", CallerFunc))
+		So(err, ShouldBeNil)
+		defer ToDefault()
+
+		Info(background, "info")
+
+		records, err := ReadLogFile(logPath, nil)
+		So(err, ShouldBeNil)
+		So(len(records), ShouldEqual, 1)
+		So(records[0].Caller, ShouldStartWith, "clog/caller_test.go:")
+		So(records[0].Ctx[funcKey], ShouldStartWith, "clog.TestCallerOptions.")
+	})
+}
@@ -0,0 +1,189 @@
+/*******************************************************************************
+ * Copyright (c) 2026 Genome Research Ltd.
+ *
+ * Author: Sendu Bala <sb10@sanger.ac.uk>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining
+ * a copy of this software and associated documentation files (the
+ * "Software"), to deal in the Software without restriction, including
+ * without limitation the rights to use, copy, modify, merge, publish,
+ * distribute, sublicense, and/or sell copies of the Software, and to
+ * permit persons to whom the Software is furnished to do so, subject to
+ * the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included
+ * in all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ ******************************************************************************/
+
+package clog
+
+// this file implements capturing of log records for tests.
+
+import (
+	"sync"
+	"sync/atomic"
+
+	log "github.com/inconshreveable/log15"
+)
+
+// TestingT is the part of testing.TB that Capture() needs.
+type TestingT interface {
+	Helper()
+	Cleanup(func())
+}
+
+// Captured holds the Records logged while capturing. Make one with Capture().
+// It is safe for concurrent use.
+type Captured struct {
+	mu      sync.RWMutex
+	records []*Record
+}
+
+// Capture makes the global logger log to the returned Captured at the given
+// level, with caller info added and secrets masked as normal. Records logged
+// with a context from ContextWithLogHandler() (or functions that use it, like
+// ContextWithFileHandler()) are also captured, in addition to going to the
+// context's handler.
+//
+// The previous global handler is restored when the given test finishes. Since
+// the global logger is shared, don't use this in parallel tests.
+func Capture(t TestingT, lvl string) *Captured {
+	t.Helper()
+
+	c := &Captured{}
+	h := createFilteredInfoHandler(c, lvlFromString(lvl))
+	previous := GetHandler()
+	remove := captures.add(h)
+
+	setRootHandler(h)
+
+	t.Cleanup(func() {
+		remove()
+		setRootHandler(previous)
+	})
+
+	return c
+}
+
+// Log implements log15.Handler, storing the given record.
+func (c *Captured) Log(r *log.Record) error {
+	record := recordFromLog15(r)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.records = append(c.records, record)
+
+	return nil
+}
+
+// Records returns the captured records that match the given query, oldest
+// first. A nil query matches all records.
+func (c *Captured) Records(q *Query) []*Record {
+	if q == nil {
+		q = &Query{}
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var matches []*Record
+
+	for _, r := range c.records {
+		if q.Matches(r) {
+			matches = append(matches, r)
+		}
+	}
+
+	return matches
+}
+
+// HasRecord returns true if a record was captured at exactly the given level
+// (valid names are as for ToDefaultAtLevel()) with the given message, and with
+// all the given alternating keys and values in its context, compared as per
+// Record.HasField().
+func (c *Captured) HasRecord(lvl, msg string, keyvals ...interface{}) bool {
+	q := &Query{Fields: make(map[string]interface{}, len(keyvals)/2)} //nolint:mnd
+
+	for i := 0; i < len(keyvals)-1; i += 2 {
+		q.Fields[fieldKey(keyvals[i])] = keyvals[i+1]
+	}
+
+	wantLvl := lvlFromString(lvl)
+
+	for _, r := range c.Records(q) {
+		if r.Lvl == wantLvl && r.Msg == msg {
+			return true
+		}
+	}
+
+	return false
+}
+
+// captureRegistry holds the handlers of active Captures.
+type captureRegistry struct {
+	active   atomic.Int32
+	mu       sync.RWMutex
+	handlers map[*log.Handler]log.Handler
+}
+
+// captures holds the handlers of active Captures, which logging with a context
+// handler also sends to.
+var captures = &captureRegistry{handlers: make(map[*log.Handler]log.Handler)} //nolint:gochecknoglobals
+
+// add registers the given handler, returning a function that unregisters it.
+func (c *captureRegistry) add(h log.Handler) func() {
+	key := &h
+
+	c.mu.Lock()
+	c.handlers[key] = h
+	c.active.Add(1)
+	c.mu.Unlock()
+
+	return func() {
+		c.mu.Lock()
+		delete(c.handlers, key)
+		c.active.Add(-1)
+		c.mu.Unlock()
+	}
+}
+
+// tee returns the given handler if there are no active Captures, otherwise
+// a handler that logs to it and then to copies of records for each Capture.
+// Only when there are active Captures is a lock taken, so that outside of
+// tests this costs no more than an atomic load.
+func (c *captureRegistry) tee(h log.Handler) log.Handler {
+	if c.active.Load() == 0 {
+		return h
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	handlers := []log.Handler{h}
+	for _, ch := range c.handlers {
+		handlers = append(handlers, copyingHandler(ch))
+	}
+
+	return log.MultiHandler(handlers...)
+}
+
+// copyingHandler returns a handler that passes a copy of each record to the
+// given handler, so that it can add to the record's context without affecting
+// other handlers.
+func copyingHandler(h log.Handler) log.Handler {
+	return log.FuncHandler(func(r *log.Record) error {
+		rc := *r
+		rc.Ctx = append(make([]interface{}, 0, len(r.Ctx)), r.Ctx...)
+
+		return h.Log(&rc)
+	})
+}
@@ -0,0 +1,110 @@
+/*******************************************************************************
+ * Copyright (c) 2026 Genome Research Ltd.
+ *
+ * Author: Sendu Bala <sb10@sanger.ac.uk>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining
+ * a copy of this software and associated documentation files (the
+ * "Software"), to deal in the Software without restriction, including
+ * without limitation the rights to use, copy, modify, merge, publish,
+ * distribute, sublicense, and/or sell copies of the Software, and to
+ * permit persons to whom the Software is furnished to do so, subject to
+ * the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included
+ * in all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ ******************************************************************************/
+
+package clog
+
+import (
+	"context"
+	"testing"
+
+	log "github.com/inconshreveable/log15"
+	. "github.com/smartystreets/goconvey/convey"
+	ft "github.com/wtsi-ssg/wr/fs/test"
+)
+
+func TestCapture(t *testing.T) {
+	background := context.Background()
+
+	Convey("Capture() captures parsed records logged at the desired level", t, func() {
+		ToDefault()
+
+		captured := Capture(t, "info")
+		ctx := ContextWithJobKey(background, "job1")
+
+		Info(ctx, "a", "n", 1)
+		Warn(ctx, "b", "password", "hunter2")
+		Debug(ctx, "c")
+
+		records := captured.Records(nil)
+		So(msgsOf(records), ShouldResemble, []string{"a", "b"})
+		So(records[0].Lvl, ShouldEqual, log.LvlInfo)
+		So(records[0].Ctx, ShouldResemble, map[string]interface{}{"jobkey": "job1", "n": 1})
+		So(records[1].Caller, ShouldStartWith, "clog/capture_test.go:")
+
+		So(captured.HasRecord("info", "a"), ShouldBeTrue)
+		So(captured.HasRecord("info", "a", "jobkey", "job1", "n", "1"), ShouldBeTrue)
+		So(captured.HasRecord("warn", "b", "password", RedactedValue), ShouldBeTrue)
+		So(captured.HasRecord("warn", "a"), ShouldBeFalse)
+		So(captured.HasRecord("info", "a", "jobkey", "job2"), ShouldBeFalse)
+		So(captured.HasRecord("debug", "c"), ShouldBeFalse)
+
+		So(msgsOf(captured.Records(&Query{Lvl: "warn"})), ShouldResemble, []string{"b"})
+
+		Convey("Including those logged to a context handler, which still gets them", func() {
+			ring := NewRingHandler(10)
+			Info(ContextWithLogHandler(ctx, ring), "d")
+
+			So(captured.HasRecord("info", "d", "jobkey", "job1"), ShouldBeTrue)
+			So(msgsOf(ring.Records(nil)), ShouldResemble, []string{"d"})
+			So(ring.Records(nil)[0].Ctx, ShouldNotContainKey, callerKey)
+		})
+	})
+
+	Convey("Capture() sees records logged to a file handler by the code under test", t, func() {
+		ToDefault()
+
+		captured := Capture(t, "info")
+		logPath := ft.FilePathInTempDir(t, "job.log")
+
+		ctx, err := ContextWithFileHandler(background, logPath, "info")
+		So(err, ShouldBeNil)
+
+		Info(ctx, "job started")
+		So(captured.HasRecord("info", "job started"), ShouldBeTrue)
+
+		records, err := ReadLogFile(logPath, nil)
+		So(err, ShouldBeNil)
+		So(msgsOf(records), ShouldResemble, []string{"job started"})
+	})
+
+	Convey("The previous handler is restored after the test", t, func() {
+		ring := ToRingAtLevel(10, "debug")
+		defer ToDefault()
+
+		var captured *Captured
+
+		t.Run("capturing", func(t *testing.T) {
+			captured = Capture(t, "debug")
+			Info(background, "a")
+			Info(ContextWithLogHandler(background, NewRingHandler(1)), "b")
+		})
+
+		Info(background, "c")
+		Info(ContextWithLogHandler(background, NewRingHandler(1)), "d")
+
+		So(msgsOf(captured.Records(nil)), ShouldResemble, []string{"a", "b"})
+		So(msgsOf(ring.Records(nil)), ShouldResemble, []string{"c"})
+	})
+}
@@ -31,11 +31,8 @@ package clog
 import (
 	"bytes"
 	"context"
-	"fmt"
 	"os"
-	"path/filepath"
 
-	"github.com/go-stack/stack"
 	log "github.com/inconshreveable/log15"
 	"github.com/sb10/l15h"
 )
@@ -44,14 +41,17 @@ import (
 // Fatal().
 var osExit = os.Exit
 
-// init sets our default logging syle.
+// init sets our default logging syle, and any levels for Named() Loggers
+// specified in the LevelsEnvVar environment variable.
 func init() {
 	ToDefault()
+	setLevelsFromEnv()
 }
 
-// ToDefault sets the global logger to log to STDERR at the "warn" level.
-func ToDefault() {
-	toOutputAtLevel(log.StderrHandler, log.LvlWarn)
+// ToDefault sets the global logger to log to STDERR at the "warn" level. Caller
+// info is added as per CallerInfoHandler() with the given options.
+func ToDefault(opts ...CallerOption) {
+	toOutputAtLevel(log.StderrHandler, log.LvlWarn, opts...)
 }
 
 // ToDefaultAtLevel sets the global logger to log to STDERR at the given level.
@@ -63,19 +63,20 @@ func ToDefaultAtLevel(lvl string) {
 // log to STDERR.
 func toStderrAtLevel(lvl log.Lvl) {
 	h := log.StreamHandler(os.Stderr, log.TerminalFormat())
-	setRootHandler(log.LvlFilterHandler(lvl, h))
+	setRootHandler(lvlFilterHandler(lvl, defaultRedactHandler(h)))
 }
 
 // ToHandlerAtLevel sets the default logger to a given custom handler at the
 // given level.
-// Eg. to log to syslog
+// Eg. to log to journald, with context as journal fields
 // ...
-// handler, _ := log15.SyslogHandler(syslog.LOG_USER,
-// "wrrunner", log15.LogfmtFormat())
+// handler, _ := clog.NewJournalHandler("", "wrrunner")
 // clog.ToHandlerAtLevel(handler, "info")
 // ...
-func ToHandlerAtLevel(outputHandler log.Handler, lvl string) {
-	toOutputAtLevel(outputHandler, lvlFromString(lvl))
+// See also NewSyslogHandler().
+// Caller info is added as per CallerInfoHandler() with the given options.
+func ToHandlerAtLevel(outputHandler log.Handler, lvl string, opts ...CallerOption) {
+	toOutputAtLevel(outputHandler, lvlFromString(lvl), opts...)
 }
 
 // GetHandler returns the global logger handler used for all logging.
@@ -84,51 +85,22 @@ func GetHandler() log.Handler {
 }
 
 // toOutputAtLevel sets the handler of the global logger to filter on the given
-// level, add caller info, and output to the given handler.
-func toOutputAtLevel(outputHandler log.Handler, lvl log.Lvl) {
-	h := createFilteredInfoHandler(outputHandler, lvl)
+// level, add caller info as per the given options, and output to the given
+// handler.
+func toOutputAtLevel(outputHandler log.Handler, lvl log.Lvl, opts ...CallerOption) {
+	h := createFilteredInfoHandler(outputHandler, lvl, opts...)
 	setRootHandler(h)
 }
 
-// CallerInfoHandler returns a Handler that, at the Debug, Warn and Error
-// levels, adds the file and line number of the calling function to the context
-// with key "caller". At the Crit levels it instead adds a stack trace to the
-// context with key "stack". The stack trace is formatted as a space separated
-// list of call sites inside matching []'s. The most recent call site is listed
-// first.
-func CallerInfoHandler(h log.Handler) log.Handler {
-	return log.FuncHandler(func(r *log.Record) error {
-		s := stack.Trace().TrimBelow(r.Call).TrimRuntime()
-		if len(s) > 0 {
-			switch r.Lvl {
-			case log.LvlInfo:
-				break
-			case log.LvlDebug, log.LvlWarn, log.LvlError:
-				var call stack.Call
-				if len(s) > 1 {
-					call = s[1]
-				} else {
-					call = s[0]
-				}
-
-				path := filepath.Join(fmt.Sprintf("%k", call), fmt.Sprintf("%v", call))
-				r.Ctx = append(r.Ctx, "caller", path)
-			case log.LvlCrit:
-				r.Ctx = append(r.Ctx, "stack", fmt.Sprintf("%+v", s))
-			}
-		}
-
-		return h.Log(r)
-	})
-}
-
-// createFilteredInfoHandler wraps the given output handler in handlers that add
-// caller info and filters on the given level.
-func createFilteredInfoHandler(outputHandler log.Handler, lvl log.Lvl) log.Handler {
-	return log.LvlFilterHandler(
+// createFilteredInfoHandler wraps the given output handler in handlers that
+// filter on the given level, add caller info as per the given options, and mask
+// secrets as per SetRedactor().
+func createFilteredInfoHandler(outputHandler log.Handler, lvl log.Lvl, opts ...CallerOption) log.Handler {
+	return lvlFilterHandler(
 		lvl,
 		CallerInfoHandler(
-			outputHandler,
+			defaultRedactHandler(outputHandler),
+			opts...,
 		),
 	)
 }
@@ -149,9 +121,9 @@ func ToBufferAtLevel(lvl string) *bytes.Buffer {
 }
 
 // ContextWithFileHandler returns a context that will log to the given file at
-// the given level.
-func ContextWithFileHandler(ctx context.Context, path, lvl string) (context.Context, error) {
-	fh, err := CreateFileHandlerAtLevel(path, lvl)
+// the given level, with caller info added as per the given options.
+func ContextWithFileHandler(ctx context.Context, path, lvl string, opts ...CallerOption) (context.Context, error) {
+	fh, err := CreateFileHandlerAtLevel(path, lvl, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -159,14 +131,22 @@ func ContextWithFileHandler(ctx context.Context, path, lvl string) (context.Cont
 	return ContextWithLogHandler(ctx, fh), nil
 }
 
-// CreateFileHandlerAtLevel returns a log15 file handler at the given level.
-func CreateFileHandlerAtLevel(path, lvl string) (log.Handler, error) {
-	fh, err := log.FileHandler(path, log.LogfmtFormat())
+// CreateFileHandlerAtLevel returns a log15 file handler at the given level, with
+// caller info added as per CallerInfoHandler() with the given options.
+func CreateFileHandlerAtLevel(path, lvl string, opts ...CallerOption) (log.Handler, error) {
+	return createFileHandlerAtLevel(path, lvl, log.LogfmtFormat(), opts)
+}
+
+// createFileHandlerAtLevel returns a log15 file handler at the given level that
+// writes records in the given format, with caller info added as per the given
+// options.
+func createFileHandlerAtLevel(path, lvl string, format log.Format, opts []CallerOption) (log.Handler, error) {
+	fh, err := log.FileHandler(path, format)
 	if err != nil {
 		return nil, err
 	}
 
-	return createFilteredInfoHandler(fh, lvlFromString(lvl)), nil
+	return createFilteredInfoHandler(fh, lvlFromString(lvl), opts...), nil
 }
 
 // AddHandler adds the given log15 handler to global logger.
@@ -175,14 +155,19 @@ func AddHandler(handler log.Handler) {
 }
 
 // ToFileAtLevel sets the global logger to log to a file at the given path
-// and at the given level.
-func ToFileAtLevel(path, lvl string) error {
-	fh, err := CreateFileHandlerAtLevel(path, lvl)
+// and at the given level, with caller info added as per the given options.
+func ToFileAtLevel(path, lvl string, opts ...CallerOption) error {
+	return setRootHandlerIfNoError(CreateFileHandlerAtLevel(path, lvl, opts...))
+}
+
+// setRootHandlerIfNoError sets the given handler as the root handler, unless
+// err is not nil, in which case it is returned.
+func setRootHandlerIfNoError(h log.Handler, err error) error {
 	if err != nil {
 		return err
 	}
 
-	setRootHandler(fh)
+	setRootHandler(h)
 
 	return nil
 }
@@ -203,47 +188,36 @@ func lvlFromString(lvl string) log.Lvl {
 func logger(ctx context.Context) log.Logger {
 	logger := log.Root()
 	if ctx != nil {
-		logger = addStringKeyToLogger(ctx, logger, retrySetKey, "retryset")
-		logger = addStringKeyToLogger(ctx, logger, retryActivityKey, "retryactivity")
-		logger = addIntKeyToLogger(ctx, logger, retryNumKey, "retrynum")
-		logger = addStringKeyToLogger(ctx, logger, contextJobKey, "jobkey")
-		logger = addStringKeyToLogger(ctx, logger, contextServerID, "serverid")
-		logger = addStringKeyToLogger(ctx, logger, contextSchedulerType, "schedulertype")
-		logger = addStringKeyToLogger(ctx, logger, contextCloudType, "cloudtype")
-		logger = addStringKeyToLogger(ctx, logger, contextCallValue, "callvalue")
-		logger = addStringKeyToLogger(ctx, logger, contextServerFlavor, "serverflavor")
+		logger = addFieldsToLogger(ctx, logger)
+		logger = addTraceToLogger(ctx, logger)
 		logger = addHandlerToLogger(ctx, logger)
 	}
 
 	return logger
 }
 
-// addStringKeyToLogger checks if the given string key is set in the logger and
-// returns a new logger with that context under the logger key if so.
-func addStringKeyToLogger(ctx context.Context, logger log.Logger, key correlationIDType, loggerKey string) log.Logger {
-	if val, ok := ctx.Value(key).(string); ok {
-		logger = logger.New(loggerKey, val)
+// addFieldsToLogger checks if any fields have been set in the context and
+// returns a new logger with those fields as context, in insertion order, if so.
+func addFieldsToLogger(ctx context.Context, logger log.Logger) log.Logger {
+	fields := fieldsFromContext(ctx)
+	if len(fields) == 0 {
+		return logger
 	}
 
-	return logger
-}
-
-// addIntKeyToLogger checks if the given int key is set in the logger and
-// returns a new logger with that context under the logger key if so.
-func addIntKeyToLogger(ctx context.Context, logger log.Logger, key correlationIDType, loggerKey string) log.Logger {
-	if val, ok := ctx.Value(key).(int); ok {
-		logger = logger.New(loggerKey, val)
+	keyvals := make([]interface{}, 0, len(fields)*2)
+	for _, f := range fields {
+		keyvals = append(keyvals, f.key, f.value)
 	}
 
-	return logger
+	return logger.New(keyvals...)
 }
 
 // addHandlerToLogger checks if a handler has been set in the context and
-// sets the logger's handler to it.
+// sets the logger's handler to it, also logging to any active Capture().
 func addHandlerToLogger(ctx context.Context, logger log.Logger) log.Logger {
 	if val, ok := ctx.Value(contextLogHandler).(log.Handler); ok {
 		logger = logger.New()
-		logger.SetHandler(val)
+		logger.SetHandler(captures.tee(val))
 	}
 
 	return logger
@@ -282,12 +256,21 @@ func Crit(ctx context.Context, msg string, args ...interface{}) {
 // Fatal logs the given message with context and args to the global logger at
 // the error level before exiting. 'fatal' is set true in stack trace.
 //
+// Before exiting, any hooks registered with OnFatal() are run, and then any
+// Flushers added with AddFlusher() are flushed.
+//
 // If the WR_FATAL_EXIT_TEST environment variable is set to 1, we don't
 // actually exit.
 func Fatal(ctx context.Context, msg string, args ...interface{}) {
 	args = append(args, "fatal", true)
 	logger(ctx).Crit(msg, args...)
 
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	atFatal.run(ctx)
+
 	if os.Getenv("WR_FATAL_EXIT_TEST") == "1" {
 		defer func() { osExit = os.Exit }()
 
@@ -122,6 +122,15 @@ func TestLogger(t *testing.T) {
 		So(buff.String(), ShouldContainSubstring, "serverflavor=bar")
 	})
 
+	Convey("Arbitrary context fields get logged in insertion order", t, func() {
+		buff := ToBufferAtLevel("debug")
+		ctx := ContextWithFields(ContextWithJobKey(background, "bar"), "rep_grp", "grp", "runner", 2)
+		ctx = ContextWithFields(ctx, "jobkey", "baz", "host", "h1")
+		Debug(ctx, "msg", "foo", 1)
+		So(buff.String(), ShouldContainSubstring, "jobkey=baz rep_grp=grp runner=2 host=h1 foo=1")
+		So(buff.String(), ShouldNotContainSubstring, "jobkey=bar")
+	})
+
 	Convey("LogHandler changes how we log", t, func() {
 		buff := ToBufferAtLevel("debug")
 		Debug(background, "msg", "foo", 1)
@@ -28,70 +28,135 @@ package clog
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/inconshreveable/log15"
 )
 
-// correlationIDType is for the *Key constants, which provide private quick-to-
-// access value storage in the With* functions.
+// correlationIDType is for the context* constants, which provide private
+// quick-to-access value storage in the ContextWith* functions.
 type correlationIDType int
 
 const (
-	retrySetKey correlationIDType = iota
-	retryActivityKey
-	retryNumKey
-	contextJobKey
-	contextServerID
-	contextSchedulerType
-	contextCloudType
-	contextCallValue
-	contextServerFlavor
+	contextFields correlationIDType = iota
 	contextLogHandler
 )
 
+// field is a key and value that has been stored in a context by
+// ContextWithFields().
+type field struct {
+	key   string
+	value interface{}
+}
+
+// ContextWithFields returns a context which knows the given alternating keys
+// and values, in addition to any fields the given context already knew. Keys
+// should be strings; other types are converted to strings with fmt.Sprint. If
+// there are an odd number of args, the last key gets a nil value.
+//
+// All the fields are logged in the order they were first added. Adding a key
+// that was already added replaces its value, but not its position.
+func ContextWithFields(ctx context.Context, keyvals ...interface{}) context.Context {
+	fields := fieldsFromContext(ctx)
+	merged := make([]field, len(fields), len(fields)+(len(keyvals)+1)/2)
+	copy(merged, fields)
+
+	for i := 0; i < len(keyvals); i += 2 {
+		var val interface{}
+		if i+1 < len(keyvals) {
+			val = keyvals[i+1]
+		}
+
+		merged = mergeField(merged, field{key: fieldKey(keyvals[i]), value: val})
+	}
+
+	return context.WithValue(ctx, contextFields, merged)
+}
+
+// fieldKey returns the given key as a string.
+func fieldKey(key interface{}) string {
+	if str, ok := key.(string); ok {
+		return str
+	}
+
+	return fmt.Sprint(key)
+}
+
+// mergeField replaces the value of the field in fields that has the same key as
+// f, or appends f if there is no such field.
+func mergeField(fields []field, f field) []field {
+	for i := range fields {
+		if fields[i].key == f.key {
+			fields[i].value = f.value
+
+			return fields
+		}
+	}
+
+	return append(fields, f)
+}
+
+// fieldsFromContext returns the fields stored in the given context by
+// ContextWithFields().
+func fieldsFromContext(ctx context.Context) []field {
+	if fields, ok := ctx.Value(contextFields).([]field); ok {
+		return fields
+	}
+
+	return nil
+}
+
+// fieldValue returns the value of the field with the given key stored in the
+// given context by ContextWithFields(), and whether there was such a field.
+func fieldValue(ctx context.Context, key string) (interface{}, bool) {
+	for _, f := range fieldsFromContext(ctx) {
+		if f.key == key {
+			return f.value, true
+		}
+	}
+
+	return nil, false
+}
+
 // ContextForRetries returns a context which knows a new unique retryset
 // ID, as well as the given retryactivity.
 func ContextForRetries(ctx context.Context, activity string) context.Context {
-	return context.WithValue(
-		context.WithValue(ctx, retrySetKey, UniqueID()),
-		retryActivityKey,
-		activity,
-	)
+	return ContextWithFields(ctx, "retryset", UniqueID(), "retryactivity", activity)
 }
 
 // ContextWithRetryNum returns a context which knows the given retrynum.
 func ContextWithRetryNum(ctx context.Context, retrynum int) context.Context {
-	return context.WithValue(ctx, retryNumKey, retrynum)
+	return ContextWithFields(ctx, "retrynum", retrynum)
 }
 
 // ContextWithJobKey returns a context which knows the given key.
 func ContextWithJobKey(ctx context.Context, key string) context.Context {
-	return context.WithValue(ctx, contextJobKey, key)
+	return ContextWithFields(ctx, "jobkey", key)
 }
 
 // ContextWithServerID returns a context which knows the given id.
 func ContextWithServerID(ctx context.Context, key string) context.Context {
-	return context.WithValue(ctx, contextServerID, key)
+	return ContextWithFields(ctx, "serverid", key)
 }
 
 // ContextWithCloudType returns a context which knows the given cloud type.
 func ContextWithCloudType(ctx context.Context, key string) context.Context {
-	return context.WithValue(ctx, contextCloudType, key)
+	return ContextWithFields(ctx, "cloudtype", key)
 }
 
 // ContextWithSchedulerType returns a context which knows the given scheduler type.
 func ContextWithSchedulerType(ctx context.Context, key string) context.Context {
-	return context.WithValue(ctx, contextSchedulerType, key)
+	return ContextWithFields(ctx, "schedulertype", key)
 }
 
 // ContextWithCallValue returns a context which knows the given call value.
 func ContextWithCallValue(ctx context.Context, key string) context.Context {
-	return context.WithValue(ctx, contextCallValue, key)
+	return ContextWithFields(ctx, "callvalue", key)
 }
 
 // ContextWithServerFlavor returns a context which knows the given server flavour.
 func ContextWithServerFlavor(ctx context.Context, key string) context.Context {
-	return context.WithValue(ctx, contextServerFlavor, key)
+	return ContextWithFields(ctx, "serverflavor", key)
 }
 
 // ContextWithLogHandler returns a context which knows the given log handler.
@@ -49,20 +49,58 @@ func TestContext(t *testing.T) {
 		So(len(id), ShouldEqual, uniqueIDLength)
 	}
 
+	Convey("ContextWithFields returns a context with the given fields", t, func() {
+		ctx := ContextWithFields(background, "rep_grp", "foo", "runner", 1)
+		val, found := fieldValue(ctx, "rep_grp")
+		So(found, ShouldBeTrue)
+		So(val, ShouldEqual, "foo")
+
+		val, found = fieldValue(ctx, "runner")
+		So(found, ShouldBeTrue)
+		So(val, ShouldEqual, 1)
+
+		_, found = fieldValue(ctx, "host")
+		So(found, ShouldBeFalse)
+
+		Convey("which can be stacked, keeping insertion order", func() {
+			ctx2 := ContextWithFields(ctx, "host", "h1", "rep_grp", "bar")
+			So(fieldsFromContext(ctx2), ShouldResemble, []field{
+				{key: "rep_grp", value: "bar"},
+				{key: "runner", value: 1},
+				{key: "host", value: "h1"},
+			})
+
+			Convey("without altering the parent context", func() {
+				So(fieldsFromContext(ctx), ShouldResemble, []field{
+					{key: "rep_grp", value: "foo"},
+					{key: "runner", value: 1},
+				})
+			})
+		})
+
+		Convey("with non-string keys converted to strings, and a missing last value set nil", func() {
+			ctx = ContextWithFields(background, 1, "one", "two")
+			So(fieldsFromContext(ctx), ShouldResemble, []field{
+				{key: "1", value: "one"},
+				{key: "two", value: nil},
+			})
+		})
+	})
+
 	Convey("ContextForRetries returns a context with a retryset and retryactivity", t, func() {
 		activity := "doing foo"
 		ctx := ContextForRetries(background, activity)
-		val := ctx.Value(retrySetKey)
+		val := fieldValueOrNil(ctx, "retryset")
 		checkValIsUniqueID(val)
 
-		val = ctx.Value(retryActivityKey)
+		val = fieldValueOrNil(ctx, "retryactivity")
 		So(checkValIsString(val), ShouldEqual, activity)
 	})
 
 	Convey("ContextWithRetryNum returns a context with a retrynum", t, func() {
 		retrynum := 3
 		ctx := ContextWithRetryNum(background, retrynum)
-		val := ctx.Value(retryNumKey)
+		val := fieldValueOrNil(ctx, "retrynum")
 		num, isInt := val.(int)
 		So(isInt, ShouldBeTrue)
 		So(num, ShouldEqual, retrynum)
@@ -73,7 +111,7 @@ func TestContext(t *testing.T) {
 		ctx := ContextWithJobKey(background, jobKey)
 		So(ctx, ShouldNotBeNil)
 
-		val := ctx.Value(contextJobKey)
+		val := fieldValueOrNil(ctx, "jobkey")
 		So(checkValIsString(val), ShouldEqual, jobKey)
 	})
 
@@ -82,7 +120,7 @@ func TestContext(t *testing.T) {
 		ctx := ContextWithServerID(background, id)
 		So(ctx, ShouldNotBeNil)
 
-		val := ctx.Value(contextServerID)
+		val := fieldValueOrNil(ctx, "serverid")
 		So(checkValIsString(val), ShouldEqual, id)
 	})
 
@@ -91,7 +129,7 @@ func TestContext(t *testing.T) {
 		ctx := ContextWithSchedulerType(background, sType)
 		So(ctx, ShouldNotBeNil)
 
-		val := ctx.Value(contextSchedulerType)
+		val := fieldValueOrNil(ctx, "schedulertype")
 		So(checkValIsString(val), ShouldEqual, sType)
 	})
 
@@ -100,7 +138,7 @@ func TestContext(t *testing.T) {
 		ctx := ContextWithCloudType(background, cType)
 		So(ctx, ShouldNotBeNil)
 
-		val := ctx.Value(contextCloudType)
+		val := fieldValueOrNil(ctx, "cloudtype")
 		So(checkValIsString(val), ShouldEqual, cType)
 	})
 
@@ -109,7 +147,7 @@ func TestContext(t *testing.T) {
 		ctx := ContextWithCallValue(background, cValue)
 		So(ctx, ShouldNotBeNil)
 
-		val := ctx.Value(contextCallValue)
+		val := fieldValueOrNil(ctx, "callvalue")
 		So(checkValIsString(val), ShouldEqual, cValue)
 	})
 
@@ -118,7 +156,7 @@ func TestContext(t *testing.T) {
 		ctx := ContextWithServerFlavor(background, sFlavor)
 		So(ctx, ShouldNotBeNil)
 
-		val := ctx.Value(contextServerFlavor)
+		val := fieldValueOrNil(ctx, "serverflavor")
 		So(checkValIsString(val), ShouldEqual, sFlavor)
 	})
 
@@ -132,3 +170,11 @@ func TestContext(t *testing.T) {
 		So(val, ShouldHaveSameTypeAs, logHandler)
 	})
 }
+
+// fieldValueOrNil returns the value of the field with the given key stored in
+// the given context, or nil.
+func fieldValueOrNil(ctx context.Context, key string) interface{} {
+	val, _ := fieldValue(ctx, key)
+
+	return val
+}
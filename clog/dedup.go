@@ -0,0 +1,178 @@
+/*******************************************************************************
+ * Copyright (c) 2026 Genome Research Ltd.
+ *
+ * Author: Sendu Bala <sb10@sanger.ac.uk>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining
+ * a copy of this software and associated documentation files (the
+ * "Software"), to deal in the Software without restriction, including
+ * without limitation the rights to use, copy, modify, merge, publish,
+ * distribute, sublicense, and/or sell copies of the Software, and to
+ * permit persons to whom the Software is furnished to do so, subject to
+ * the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included
+ * in all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ ******************************************************************************/
+
+package clog
+
+// this file implements a handler that suppresses repeated log records.
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/inconshreveable/log15"
+)
+
+// RepeatsKey is the context key that DedupHandler summary records store the
+// number of suppressed repeats under.
+const RepeatsKey = "repeats"
+
+// dedupEntry tracks the suppressed repeats of a record.
+type dedupEntry struct {
+	last    *log.Record
+	repeats int
+}
+
+// DedupHandler is a log15 handler that collapses identical records logged
+// within a time window. Make one with NewDedupHandler(). It is safe for
+// concurrent use.
+type DedupHandler struct {
+	handler log.Handler
+	window  time.Duration
+	keys    []string
+	mu      sync.Mutex
+	entries map[string]*dedupEntry
+	timers  map[string]*time.Timer
+}
+
+// NewDedupHandler returns a DedupHandler that passes the first of a set of
+// identical records to the given handler straight away, then suppresses
+// identical records for the given window of time. When the window closes, if
+// any were suppressed, the last of them is passed on as a summary, with the
+// number suppressed added to its context with key "repeats".
+//
+// Records are identical if they have the same level and message, and the same
+// values for the given context keys (eg. "retryset", "jobkey"). Other context
+// values, such as a sleep duration, are ignored.
+func NewDedupHandler(h log.Handler, window time.Duration, keys ...string) *DedupHandler {
+	return &DedupHandler{
+		handler: h,
+		window:  window,
+		keys:    keys,
+		entries: make(map[string]*dedupEntry),
+		timers:  make(map[string]*time.Timer),
+	}
+}
+
+// Log implements log15.Handler.
+func (d *DedupHandler) Log(r *log.Record) error {
+	id := d.identify(r)
+
+	d.mu.Lock()
+
+	if entry, seen := d.entries[id]; seen {
+		entry.last = r
+		entry.repeats++
+		d.mu.Unlock()
+
+		return nil
+	}
+
+	entry := &dedupEntry{}
+	d.entries[id] = entry
+	d.timers[id] = time.AfterFunc(d.window, func() { d.closeWindow(id, entry) })
+	d.mu.Unlock()
+
+	return d.handler.Log(r)
+}
+
+// identify returns a string that is the same for identical records.
+func (d *DedupHandler) identify(r *log.Record) string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "%d\x00%s", r.Lvl, r.Msg)
+
+	for _, key := range d.keys {
+		fmt.Fprintf(&sb, "\x00%v", recordValue(r, key))
+	}
+
+	return sb.String()
+}
+
+// recordValue returns the value of the given key in the record's context, or
+// nil.
+func recordValue(r *log.Record, key string) interface{} {
+	for i := 0; i < len(r.Ctx)-1; i += 2 {
+		if r.Ctx[i] == key {
+			return r.Ctx[i+1]
+		}
+	}
+
+	return nil
+}
+
+// closeWindow forgets the given entry for the record with the given id,
+// passing on a summary if any repeats of it were suppressed. Does nothing if
+// the entry was already forgotten by Flush().
+func (d *DedupHandler) closeWindow(id string, entry *dedupEntry) {
+	d.mu.Lock()
+	if d.entries[id] != entry {
+		d.mu.Unlock()
+
+		return
+	}
+
+	delete(d.entries, id)
+	delete(d.timers, id)
+	d.mu.Unlock()
+
+	d.logSummary(entry)
+}
+
+// logSummary passes on the last suppressed record of the given entry, with the
+// number of repeats added, if there were any.
+func (d *DedupHandler) logSummary(entry *dedupEntry) {
+	if entry.repeats == 0 {
+		return
+	}
+
+	summary := *entry.last
+	summary.Ctx = append(append([]interface{}{}, entry.last.Ctx...), RepeatsKey, entry.repeats)
+
+	d.handler.Log(&summary) //nolint:errcheck
+}
+
+// Flush implements Flusher, closing all current windows early and passing on
+// summaries of any suppressed records. It always returns nil.
+func (d *DedupHandler) Flush(ctx context.Context) error {
+	d.mu.Lock()
+	entries := d.entries
+	d.entries = make(map[string]*dedupEntry)
+
+	for _, timer := range d.timers {
+		timer.Stop()
+	}
+
+	d.timers = make(map[string]*time.Timer)
+	d.mu.Unlock()
+
+	for _, entry := range entries {
+		d.logSummary(entry)
+	}
+
+	return nil
+}
@@ -0,0 +1,151 @@
+/*******************************************************************************
+ * Copyright (c) 2026 Genome Research Ltd.
+ *
+ * Author: Sendu Bala <sb10@sanger.ac.uk>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining
+ * a copy of this software and associated documentation files (the
+ * "Software"), to deal in the Software without restriction, including
+ * without limitation the rights to use, copy, modify, merge, publish,
+ * distribute, sublicense, and/or sell copies of the Software, and to
+ * permit persons to whom the Software is furnished to do so, subject to
+ * the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included
+ * in all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ ******************************************************************************/
+
+package clog
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDedup(t *testing.T) {
+	background := context.Background()
+
+	Convey("A DedupHandler collapses identical records within a window", t, func() {
+		ring := NewRingHandler(100)
+		window := 50 * time.Millisecond
+		dedup := NewDedupHandler(ring, window, "retryset")
+		ToHandlerAtLevel(dedup, "debug")
+		defer ToDefault()
+
+		ctx := ContextForRetries(background, "doing foo")
+		for i := 0; i < 5; i++ {
+			Debug(ctx, "backoff", "sleep", i)
+		}
+
+		Debug(ctx, "other")
+		Info(ctx, "backoff")
+		Debug(ContextForRetries(background, "doing foo"), "backoff")
+
+		records := ring.Records(nil)
+		So(msgsOf(records), ShouldResemble, []string{"backoff", "other", "backoff", "backoff"})
+		So(records[0].HasField("sleep", 0), ShouldBeTrue)
+		So(records[0].Ctx[RepeatsKey], ShouldBeNil)
+
+		Convey("emitting a summary with a repeat count when the window closes", func() {
+			So(waitForRecords(ring, 5, 10*window), ShouldBeTrue)
+
+			records = ring.Records(nil)
+			So(len(records), ShouldEqual, 5)
+			So(records[4].Msg, ShouldEqual, "backoff")
+			So(records[4].HasField(RepeatsKey, 4), ShouldBeTrue)
+			So(records[4].HasField("sleep", 4), ShouldBeTrue)
+
+			Convey("after which the next identical record is passed on", func() {
+				Debug(ctx, "backoff", "sleep", 5)
+				records = ring.Records(nil)
+				So(len(records), ShouldEqual, 6)
+				So(records[5].Ctx[RepeatsKey], ShouldBeNil)
+			})
+		})
+
+		Convey("Flush emits summaries early", func() {
+			So(dedup.Flush(background), ShouldBeNil)
+			records = ring.Records(nil)
+			So(len(records), ShouldEqual, 5)
+			So(records[4].HasField(RepeatsKey, 4), ShouldBeTrue)
+
+			<-time.After(2 * window)
+			So(ring.Len(), ShouldEqual, 5)
+		})
+	})
+
+	Convey("A DedupHandler with no keys only considers level and message", t, func() {
+		ring := NewRingHandler(100)
+		dedup := NewDedupHandler(ring, time.Hour)
+		ctx := ContextWithLogHandler(background, dedup)
+
+		Warn(ContextWithFields(ctx, "jobkey", "a"), "msg")
+		Warn(ContextWithFields(ctx, "jobkey", "b"), "msg")
+		So(ring.Len(), ShouldEqual, 1)
+
+		So(dedup.Flush(background), ShouldBeNil)
+		records := ring.Records(nil)
+		So(len(records), ShouldEqual, 2)
+		So(records[1].HasField("jobkey", "b"), ShouldBeTrue)
+		So(records[1].HasField(RepeatsKey, 1), ShouldBeTrue)
+	})
+
+	Convey("A DedupHandler can be used concurrently", t, func() {
+		ring := NewRingHandler(100)
+		dedup := NewDedupHandler(ring, time.Millisecond)
+		ctx := ContextWithLogHandler(background, dedup)
+		wg := &sync.WaitGroup{}
+		n := 100
+
+		wg.Add(n)
+		for i := 0; i < n; i++ {
+			go func() {
+				defer wg.Done()
+				Warn(ctx, "msg")
+			}()
+		}
+		wg.Wait()
+		So(dedup.Flush(background), ShouldBeNil)
+
+		total := 0
+		for _, r := range ring.Records(nil) {
+			total++
+			if repeats, ok := r.Ctx[RepeatsKey].(int); ok {
+				total += repeats - 1
+			}
+		}
+		So(total, ShouldEqual, n)
+	})
+}
+
+// waitForRecords waits up to the given timeout for the ring to contain n
+// records, returning true if it does.
+func waitForRecords(ring *RingHandler, n int, timeout time.Duration) bool {
+	limit := time.After(timeout)
+	ticker := time.NewTicker(time.Millisecond)
+
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if ring.Len() >= n {
+				return true
+			}
+		case <-limit:
+			return false
+		}
+	}
+}
@@ -0,0 +1,203 @@
+/*******************************************************************************
+ * Copyright (c) 2026 Genome Research Ltd.
+ *
+ * Author: Sendu Bala <sb10@sanger.ac.uk>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining
+ * a copy of this software and associated documentation files (the
+ * "Software"), to deal in the Software without restriction, including
+ * without limitation the rights to use, copy, modify, merge, publish,
+ * distribute, sublicense, and/or sell copies of the Software, and to
+ * permit persons to whom the Software is furnished to do so, subject to
+ * the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included
+ * in all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ ******************************************************************************/
+
+package clog
+
+// this file implements the things Fatal() does before exiting.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultFatalTimeout is the default for SetFatalTimeout().
+const DefaultFatalTimeout = 10 * time.Second
+
+var errFatalHookPanicked = errors.New("fatal hook panicked")
+
+// FatalHook is a function registered with OnFatal(). The context it receives
+// will be cancelled if the hooks take too long. It should return an error if
+// it failed.
+type FatalHook func(ctx context.Context) error
+
+// Flusher is implemented by handlers that hold on to records before writing
+// them, such as DedupHandler.
+type Flusher interface {
+	// Flush writes out any held records, stopping early if the context is
+	// cancelled.
+	Flush(ctx context.Context) error
+}
+
+// namedHook is a FatalHook with its name.
+type namedHook struct {
+	name string
+	hook FatalHook
+}
+
+// registeredFlusher wraps a Flusher, so that it can be unregistered.
+type registeredFlusher struct {
+	flusher Flusher
+}
+
+// fatalActions holds the things that Fatal() does before exiting. It is safe
+// for concurrent use.
+type fatalActions struct {
+	mu       sync.Mutex
+	hooks    []*namedHook
+	flushers []*registeredFlusher
+	timeout  time.Duration
+}
+
+// atFatal holds the hooks and flushers added by OnFatal() and AddFlusher().
+var atFatal = &fatalActions{timeout: DefaultFatalTimeout} //nolint:gochecknoglobals
+
+// OnFatal registers the given hook to be run by Fatal() before it exits. Hooks
+// are run one at a time, in the reverse order they were registered, all within
+// the timeout set by SetFatalTimeout(). Hooks that return an error, panic or
+// time out are logged at the error level, with the given name.
+//
+// The returned function unregisters the hook.
+func OnFatal(name string, hook FatalHook) func() {
+	nh := &namedHook{name: name, hook: hook}
+
+	atFatal.mu.Lock()
+	atFatal.hooks = append(atFatal.hooks, nh)
+	atFatal.mu.Unlock()
+
+	return func() {
+		atFatal.mu.Lock()
+		defer atFatal.mu.Unlock()
+
+		for i, h := range atFatal.hooks {
+			if h == nh {
+				atFatal.hooks = append(atFatal.hooks[:i], atFatal.hooks[i+1:]...)
+
+				return
+			}
+		}
+	}
+}
+
+// AddFlusher registers the given Flusher to be flushed by Fatal() after it has
+// run the OnFatal() hooks and before it exits. Flushing is done within whatever
+// remains of the timeout set by SetFatalTimeout() after the hooks have run.
+// Flushers are still called if the hooks used up all the time, so they can
+// write out what they can without waiting.
+//
+// The returned function unregisters the Flusher.
+func AddFlusher(f Flusher) func() {
+	rf := &registeredFlusher{flusher: f}
+
+	atFatal.mu.Lock()
+	atFatal.flushers = append(atFatal.flushers, rf)
+	atFatal.mu.Unlock()
+
+	return func() {
+		atFatal.mu.Lock()
+		defer atFatal.mu.Unlock()
+
+		for i, f := range atFatal.flushers {
+			if f == rf {
+				atFatal.flushers = append(atFatal.flushers[:i], atFatal.flushers[i+1:]...)
+
+				return
+			}
+		}
+	}
+}
+
+// SetFatalTimeout sets how long Fatal() will wait in total for all the
+// OnFatal() hooks to complete and all the Flushers to flush, before it exits.
+// Defaults to DefaultFatalTimeout.
+func SetFatalTimeout(timeout time.Duration) {
+	atFatal.mu.Lock()
+	defer atFatal.mu.Unlock()
+
+	atFatal.timeout = timeout
+}
+
+// snapshot returns copies of our hooks and flushers, and our timeout.
+func (f *fatalActions) snapshot() ([]*namedHook, []*registeredFlusher, time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]*namedHook{}, f.hooks...), append([]*registeredFlusher{}, f.flushers...), f.timeout
+}
+
+// run runs our hooks in reverse order, then flushes our flushers, all within a
+// single deadline of our timeout. The given context is used for logging, and as
+// the parent of the context passed to hooks and flushers, though its own
+// cancellation is ignored.
+func (f *fatalActions) run(ctx context.Context) {
+	hooks, flushers, timeout := f.snapshot()
+
+	deadlineCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		runFatalHook(deadlineCtx, hooks[i])
+	}
+
+	for _, rf := range flushers {
+		if err := rf.flusher.Flush(deadlineCtx); err != nil {
+			Error(ctx, "log handler flush failed", "err", err)
+		}
+	}
+}
+
+// runFatalHook runs the given hook, logging if it fails or doesn't complete
+// before the context is cancelled. If the context is already cancelled, the
+// hook is not run.
+func runFatalHook(ctx context.Context, nh *namedHook) {
+	if ctx.Err() != nil {
+		Error(ctx, "fatal hook skipped after timeout", "hook", nh.name)
+
+		return
+	}
+
+	errCh := make(chan error, 1)
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				errCh <- fmt.Errorf("%w: %v", errFatalHookPanicked, r)
+			}
+		}()
+
+		errCh <- nh.hook(ctx)
+	}()
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			Error(ctx, "fatal hook failed", "hook", nh.name, "err", err)
+		}
+	case <-ctx.Done():
+		Error(ctx, "fatal hook timed out", "hook", nh.name)
+	}
+}
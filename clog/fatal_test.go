@@ -0,0 +1,179 @@
+/*******************************************************************************
+ * Copyright (c) 2026 Genome Research Ltd.
+ *
+ * Author: Sendu Bala <sb10@sanger.ac.uk>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining
+ * a copy of this software and associated documentation files (the
+ * "Software"), to deal in the Software without restriction, including
+ * without limitation the rights to use, copy, modify, merge, publish,
+ * distribute, sublicense, and/or sell copies of the Software, and to
+ * permit persons to whom the Software is furnished to do so, subject to
+ * the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included
+ * in all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ ******************************************************************************/
+
+package clog
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var errHook = errors.New("hook err")
+
+// mockFlusher is a Flusher that records when it was flushed.
+type mockFlusher struct {
+	mu       sync.Mutex
+	flushed  bool
+	err      error
+	order    *[]string
+	deadline time.Time
+}
+
+// Flush records that it was called, returning our err.
+func (m *mockFlusher) Flush(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.flushed = true
+	*m.order = append(*m.order, "flush")
+	m.deadline, _ = ctx.Deadline()
+
+	return m.err
+}
+
+func TestFatal(t *testing.T) {
+	background := context.Background()
+
+	Convey("Given some registered fatal hooks and flushers", t, func() {
+		os.Setenv("WR_FATAL_EXIT_TEST", "1")
+		defer os.Unsetenv("WR_FATAL_EXIT_TEST")
+
+		ring := ToRingAtLevel(100, "debug")
+		defer ToDefault()
+
+		var order []string
+
+		hookFor := func(name string, err error) FatalHook {
+			return func(ctx context.Context) error {
+				order = append(order, name)
+
+				return err
+			}
+		}
+
+		removeA := OnFatal("a", hookFor("a", nil))
+		removeB := OnFatal("b", hookFor("b", errHook))
+		removeC := OnFatal("c", hookFor("c", nil))
+		flusher := &mockFlusher{order: &order}
+		removeF := AddFlusher(flusher)
+
+		defer func() {
+			removeA()
+			removeB()
+			removeC()
+			removeF()
+		}()
+
+		Convey("Fatal runs the hooks in reverse order, then flushes, logging failures", func() {
+			Fatal(ContextWithJobKey(background, "bar"), "msg")
+			So(order, ShouldResemble, []string{"c", "b", "a", "flush"})
+			So(flusher.flushed, ShouldBeTrue)
+
+			records := ring.Records(nil)
+			So(msgsOf(records), ShouldResemble, []string{"msg", "fatal hook failed"})
+			So(records[1].HasField("hook", "b"), ShouldBeTrue)
+			So(records[1].HasField("err", errHook), ShouldBeTrue)
+			So(records[1].HasField("jobkey", "bar"), ShouldBeTrue)
+		})
+
+		Convey("Removed hooks and flushers are not run", func() {
+			removeB()
+			removeF()
+			Fatal(background, "msg")
+			So(order, ShouldResemble, []string{"c", "a"})
+			So(flusher.flushed, ShouldBeFalse)
+		})
+
+		Convey("Hooks that panic are logged", func() {
+			removeP := OnFatal("p", func(ctx context.Context) error { panic("oops") })
+			defer removeP()
+
+			Fatal(background, "msg")
+			So(order, ShouldResemble, []string{"c", "b", "a", "flush"})
+
+			records := ring.Records(&Query{Fields: map[string]interface{}{"hook": "p"}})
+			So(len(records), ShouldEqual, 1)
+			err, ok := records[0].Ctx["err"].(error)
+			So(ok, ShouldBeTrue)
+			So(errors.Is(err, errFatalHookPanicked), ShouldBeTrue)
+			So(err.Error(), ShouldEqual, "fatal hook panicked: oops")
+		})
+
+		Convey("Hooks that take too long are abandoned and logged, but flushing still happens", func() {
+			SetFatalTimeout(10 * time.Millisecond)
+			defer SetFatalTimeout(DefaultFatalTimeout)
+
+			removeS := OnFatal("slow", func(ctx context.Context) error {
+				<-ctx.Done()
+				<-time.After(time.Second)
+
+				return nil
+			})
+			defer removeS()
+
+			start := time.Now()
+			Fatal(background, "msg")
+			So(time.Since(start), ShouldBeLessThan, time.Second)
+			So(flusher.flushed, ShouldBeTrue)
+
+			records := ring.Records(&Query{Lvl: "error"})
+			So(msgsOf(records), ShouldResemble, []string{
+				"msg", "fatal hook timed out", "fatal hook skipped after timeout",
+				"fatal hook skipped after timeout", "fatal hook skipped after timeout",
+			})
+			So(order, ShouldResemble, []string{"flush"})
+		})
+
+		Convey("Hooks and flushers share a single deadline", func() {
+			var hookDeadline time.Time
+
+			removeD := OnFatal("d", func(ctx context.Context) error {
+				hookDeadline, _ = ctx.Deadline()
+
+				return nil
+			})
+			defer removeD()
+
+			start := time.Now()
+			Fatal(background, "msg")
+			So(hookDeadline, ShouldHappenBetween, start, start.Add(DefaultFatalTimeout+time.Second))
+			So(flusher.deadline, ShouldEqual, hookDeadline)
+		})
+
+		Convey("Flush failures are logged", func() {
+			flusher.err = errHook
+			Fatal(background, "msg")
+
+			records := ring.Records(&Query{Fields: map[string]interface{}{"err": errHook}})
+			So(msgsOf(records), ShouldResemble, []string{"fatal hook failed", "log handler flush failed"})
+		})
+	})
+}
@@ -0,0 +1,151 @@
+/*******************************************************************************
+ * Copyright (c) 2026 Genome Research Ltd.
+ *
+ * Author: Sendu Bala <sb10@sanger.ac.uk>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining
+ * a copy of this software and associated documentation files (the
+ * "Software"), to deal in the Software without restriction, including
+ * without limitation the rights to use, copy, modify, merge, publish,
+ * distribute, sublicense, and/or sell copies of the Software, and to
+ * permit persons to whom the Software is furnished to do so, subject to
+ * the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included
+ * in all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ ******************************************************************************/
+
+package clog
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	ft "github.com/wtsi-ssg/wr/fs/test"
+)
+
+// listenUnixgram returns a unix datagram socket listening on a new path in a
+// temp dir, along with that path.
+func listenUnixgram(t *testing.T) (*net.UnixConn, string) {
+	t.Helper()
+
+	path := ft.FilePathInTempDir(t, "sock")
+
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { conn.Close() })
+
+	return conn, path
+}
+
+// readDatagram returns the next datagram received by the given socket, or nil
+// if none arrives within a second.
+func readDatagram(conn *net.UnixConn) []byte {
+	buf := make([]byte, 65536)
+
+	if err := conn.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		return nil
+	}
+
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil
+	}
+
+	return buf[:n]
+}
+
+// parseJournalDatagram parses a datagram in journald's native format.
+func parseJournalDatagram(data []byte) map[string]string {
+	fields := make(map[string]string)
+
+	for len(data) > 0 {
+		nl := bytes.IndexByte(data, '\n')
+		line := string(data[:nl])
+		data = data[nl+1:]
+
+		if name, value, found := strings.Cut(line, "="); found {
+			fields[name] = value
+
+			continue
+		}
+
+		size := binary.LittleEndian.Uint64(data[:8])
+		fields[line] = string(data[8 : 8+size])
+		data = data[8+size+1:]
+	}
+
+	return fields
+}
+
+func TestJournal(t *testing.T) {
+	background := context.Background()
+
+	Convey("journalFieldName makes valid journal field names", t, func() {
+		So(journalFieldName("jobkey"), ShouldEqual, "JOBKEY")
+		So(journalFieldName("server-id.x"), ShouldEqual, "SERVER_ID_X")
+		So(journalFieldName("_private"), ShouldEqual, "PRIVATE")
+		So(journalFieldName("9lives"), ShouldEqual, "F_9LIVES")
+		So(journalFieldName("__"), ShouldEqual, "F_")
+		So(len(journalFieldName(strings.Repeat("a", 100))), ShouldEqual, maxJournalFieldNameLength)
+	})
+
+	Convey("Given a JournalHandler sending to a stand-in journald socket", t, func() {
+		conn, path := listenUnixgram(t)
+
+		jh, err := NewJournalHandler(path, "wrtest")
+		So(err, ShouldBeNil)
+		defer jh.Close()
+
+		ToHandlerAtLevel(jh, "debug")
+		defer ToDefault()
+
+		Convey("records are sent with context as journal fields and level as PRIORITY", func() {
+			ctx := ContextWithServerFlavor(ContextWithSchedulerType(
+				ContextWithServerID(ContextWithJobKey(background, "job1"), "server1"), "lsf"), "small")
+			Warn(ctx, "a msg", "multi", "line1\nline2")
+
+			fields := parseJournalDatagram(readDatagram(conn))
+			So(fields["MESSAGE"], ShouldEqual, "a msg")
+			So(fields["PRIORITY"], ShouldEqual, "4")
+			So(fields["SYSLOG_IDENTIFIER"], ShouldEqual, "wrtest")
+			So(fields["JOBKEY"], ShouldEqual, "job1")
+			So(fields["SERVERID"], ShouldEqual, "server1")
+			So(fields["SCHEDULERTYPE"], ShouldEqual, "lsf")
+			So(fields["SERVERFLAVOR"], ShouldEqual, "small")
+			So(fields["MULTI"], ShouldEqual, "line1\nline2")
+			So(fields["CALLER"], ShouldStartWith, "clog/journal_test.go:")
+
+			Crit(background, "crit")
+
+			fields = parseJournalDatagram(readDatagram(conn))
+			So(fields["PRIORITY"], ShouldEqual, "2")
+			So(fields["STACK"], ShouldContainSubstring, "clog/journal_test.go:")
+
+			Debug(background, "debug")
+			So(parseJournalDatagram(readDatagram(conn))["PRIORITY"], ShouldEqual, "7")
+		})
+	})
+
+	Convey("NewJournalHandler fails if there's no journald socket", t, func() {
+		_, err := NewJournalHandler(ft.FilePathInTempDir(t, "missing"), "wrtest")
+		So(err, ShouldNotBeNil)
+	})
+}
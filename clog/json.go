@@ -0,0 +1,70 @@
+/*******************************************************************************
+ * Copyright (c) 2026 Genome Research Ltd.
+ *
+ * Author: Sendu Bala <sb10@sanger.ac.uk>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining
+ * a copy of this software and associated documentation files (the
+ * "Software"), to deal in the Software without restriction, including
+ * without limitation the rights to use, copy, modify, merge, publish,
+ * distribute, sublicense, and/or sell copies of the Software, and to
+ * permit persons to whom the Software is furnished to do so, subject to
+ * the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included
+ * in all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ ******************************************************************************/
+
+package clog
+
+import (
+	"bytes"
+	"context"
+
+	log "github.com/inconshreveable/log15"
+)
+
+// ToJSONBufferAtLevel sets the global logger to log JSON lines to the returned
+// bytes.Buffer at the given level. Each line is a JSON object where context
+// values keep their types, eg. retrynum is a number and stack is an array.
+func ToJSONBufferAtLevel(lvl string) *bytes.Buffer {
+	buff := new(bytes.Buffer)
+	toOutputAtLevel(log.StreamHandler(buff, log.JsonFormat()), lvlFromString(lvl))
+
+	return buff
+}
+
+// ContextWithJSONFileHandler returns a context that will log JSON lines to the
+// given file at the given level, with caller info added as per the given
+// options.
+func ContextWithJSONFileHandler(ctx context.Context, path, lvl string,
+	opts ...CallerOption) (context.Context, error) {
+	fh, err := CreateJSONFileHandlerAtLevel(path, lvl, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return ContextWithLogHandler(ctx, fh), nil
+}
+
+// CreateJSONFileHandlerAtLevel returns a log15 file handler at the given level
+// that logs JSON lines, with caller info added as per CallerInfoHandler() with
+// the given options.
+func CreateJSONFileHandlerAtLevel(path, lvl string, opts ...CallerOption) (log.Handler, error) {
+	return createFileHandlerAtLevel(path, lvl, log.JsonFormat(), opts)
+}
+
+// ToJSONFileAtLevel sets the global logger to log JSON lines to a file at the
+// given path and at the given level, with caller info added as per the given
+// options.
+func ToJSONFileAtLevel(path, lvl string, opts ...CallerOption) error {
+	return setRootHandlerIfNoError(CreateJSONFileHandlerAtLevel(path, lvl, opts...))
+}
@@ -0,0 +1,168 @@
+/*******************************************************************************
+ * Copyright (c) 2026 Genome Research Ltd.
+ *
+ * Author: Sendu Bala <sb10@sanger.ac.uk>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining
+ * a copy of this software and associated documentation files (the
+ * "Software"), to deal in the Software without restriction, including
+ * without limitation the rights to use, copy, modify, merge, publish,
+ * distribute, sublicense, and/or sell copies of the Software, and to
+ * permit persons to whom the Software is furnished to do so, subject to
+ * the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included
+ * in all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ ******************************************************************************/
+
+package clog
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	ft "github.com/wtsi-ssg/wr/fs/test"
+)
+
+func TestJSON(t *testing.T) {
+	background := context.Background()
+
+	Convey("You can log JSON lines to a buffer", t, func() {
+		buff := ToJSONBufferAtLevel("debug")
+		defer ToDefault()
+
+		ctx := ContextWithRetryNum(ContextWithJobKey(background, "bar"), 3)
+		Debug(ctx, "msg", "foo", 1)
+
+		records := decodeJSONLines(buff)
+		So(len(records), ShouldEqual, 1)
+		So(records[0]["lvl"], ShouldEqual, "dbug")
+		So(records[0]["msg"], ShouldEqual, "msg")
+		So(records[0]["t"], ShouldNotBeEmpty)
+
+		Convey("with context fields kept as typed values", func() {
+			So(records[0]["foo"], ShouldEqual, 1)
+			So(records[0]["jobkey"], ShouldEqual, "bar")
+			So(records[0]["retrynum"], ShouldEqual, 3)
+			So(records[0]["caller"], ShouldStartWith, "clog/json_test.go:")
+		})
+
+		Convey("with Crit stacks as arrays of call sites", func() {
+			buff.Reset()
+			Crit(ctx, "msg")
+
+			records = decodeJSONLines(buff)
+			So(len(records), ShouldEqual, 1)
+			stack, ok := records[0]["stack"].([]interface{})
+			So(ok, ShouldBeTrue)
+			So(len(stack), ShouldBeGreaterThan, 1)
+			So(stack[0], ShouldStartWith, "github.com/wtsi-ssg/wr/clog/clog.go:")
+			So(stack[1], ShouldStartWith, "github.com/wtsi-ssg/wr/clog/json_test.go:")
+		})
+
+		Convey("but not below the given level", func() {
+			buff = ToJSONBufferAtLevel("warn")
+			Debug(ctx, "msg")
+			So(buff.String(), ShouldBeBlank)
+		})
+	})
+
+	Convey("You can log JSON lines to a file", t, func() {
+		logPath := ft.FilePathInTempDir(t, "clog.json")
+		err := ToJSONFileAtLevel(logPath, "info")
+		So(err, ShouldBeNil)
+		defer ToDefault()
+
+		Info(ContextWithServerID(background, "srv"), "msg", "foo", 1)
+		Debug(background, "debug")
+
+		records := decodeJSONFile(logPath)
+		So(len(records), ShouldEqual, 1)
+		So(records[0]["msg"], ShouldEqual, "msg")
+		So(records[0]["serverid"], ShouldEqual, "srv")
+		So(records[0]["foo"], ShouldEqual, 1)
+
+		Convey("Unless the path is invalid", func() {
+			So(ToJSONFileAtLevel("", "info"), ShouldNotBeNil)
+		})
+	})
+
+	Convey("Context with a JSON file handler logs JSON to a given file", t, func() {
+		buff := ToBufferAtLevel("debug")
+		defer ToDefault()
+
+		logPath := ft.FilePathInTempDir(t, "clog.json")
+		ctxf, err := ContextWithJSONFileHandler(ContextWithJobKey(background, "bar"), logPath, "debug")
+		So(err, ShouldBeNil)
+		So(ctxf, ShouldNotBeNil)
+
+		Warn(ctxf, "msg", "foo", 1)
+		So(buff.String(), ShouldBeBlank)
+
+		records := decodeJSONFile(logPath)
+		So(len(records), ShouldEqual, 1)
+		So(records[0]["lvl"], ShouldEqual, "warn")
+		So(records[0]["jobkey"], ShouldEqual, "bar")
+		So(records[0]["caller"], ShouldStartWith, "clog/json_test.go:")
+
+		Convey("Unless the path is invalid", func() {
+			ctxf, err = ContextWithJSONFileHandler(background, "", "debug")
+			So(ctxf, ShouldBeNil)
+			So(err, ShouldNotBeNil)
+
+			fh, err := CreateJSONFileHandlerAtLevel("", "debug")
+			So(fh, ShouldBeNil)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+// decodeJSONLines decodes each line in the given buffer as a JSON object. JSON
+// numbers are decoded as ints for easy comparison.
+func decodeJSONLines(buff *bytes.Buffer) []map[string]interface{} {
+	var records []map[string]interface{}
+
+	scanner := bufio.NewScanner(strings.NewReader(buff.String()))
+	for scanner.Scan() {
+		decoder := json.NewDecoder(strings.NewReader(scanner.Text()))
+		decoder.UseNumber()
+
+		record := make(map[string]interface{})
+		So(decoder.Decode(&record), ShouldBeNil)
+
+		for key, val := range record {
+			if num, ok := val.(json.Number); ok {
+				i, err := num.Int64()
+				So(err, ShouldBeNil)
+				record[key] = int(i)
+			}
+		}
+
+		records = append(records, record)
+	}
+
+	return records
+}
+
+// decodeJSONFile decodes each line in the file at the given path as a JSON
+// object.
+func decodeJSONFile(path string) []map[string]interface{} {
+	content, err := os.ReadFile(path)
+	So(err, ShouldBeNil)
+
+	return decodeJSONLines(bytes.NewBuffer(content))
+}
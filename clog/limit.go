@@ -0,0 +1,158 @@
+/*******************************************************************************
+ * Copyright (c) 2026 Genome Research Ltd.
+ *
+ * Author: Sendu Bala <sb10@sanger.ac.uk>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining
+ * a copy of this software and associated documentation files (the
+ * "Software"), to deal in the Software without restriction, including
+ * without limitation the rights to use, copy, modify, merge, publish,
+ * distribute, sublicense, and/or sell copies of the Software, and to
+ * permit persons to whom the Software is furnished to do so, subject to
+ * the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included
+ * in all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ ******************************************************************************/
+
+package clog
+
+// this file implements a handler that limits the rate of log records.
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/inconshreveable/log15"
+)
+
+// DroppedKey is the context key that RateLimitHandler stores the number of
+// dropped records under.
+const DroppedKey = "dropped"
+
+// RateLimit describes a token bucket: up to Burst records can be logged at
+// once, with the allowance refilling at Rate records per second.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// tokenBucket implements the token bucket algorithm for a RateLimit.
+type tokenBucket struct {
+	limit   RateLimit
+	tokens  float64
+	last    time.Time
+	dropped int
+}
+
+// take refills the bucket based on the time since the last call, and then
+// takes a token if one is available, returning true if it did.
+func (b *tokenBucket) take(now time.Time) bool {
+	b.tokens += now.Sub(b.last).Seconds() * b.limit.Rate
+	b.last = now
+
+	if b.tokens > float64(b.limit.Burst) {
+		b.tokens = float64(b.limit.Burst)
+	}
+
+	if b.tokens < 1 {
+		b.dropped++
+
+		return false
+	}
+
+	b.tokens--
+
+	return true
+}
+
+// RateLimitHandler is a log15 handler that drops records that exceed a
+// per-level rate limit. Make one with NewRateLimitHandler(). It is safe for
+// concurrent use.
+type RateLimitHandler struct {
+	handler log.Handler
+	now     func() time.Time
+	mu      sync.Mutex
+	buckets map[log.Lvl]*tokenBucket
+}
+
+// NewRateLimitHandler returns a RateLimitHandler that passes records to the
+// given handler, as long as they don't exceed the limit for their level.
+// limits is keyed on level names (valid names are as for ToDefaultAtLevel());
+// levels without a limit are not limited.
+//
+// When records at a level start being allowed again after some were dropped,
+// a warning saying how many were dropped is passed on first, with the number
+// stored in its context with key "dropped".
+func NewRateLimitHandler(h log.Handler, limits map[string]RateLimit) *RateLimitHandler {
+	l := &RateLimitHandler{
+		handler: h,
+		now:     time.Now,
+		buckets: make(map[log.Lvl]*tokenBucket, len(limits)),
+	}
+
+	start := l.now()
+
+	for lvl, limit := range limits {
+		l.buckets[lvlFromString(lvl)] = &tokenBucket{limit: limit, tokens: float64(limit.Burst), last: start}
+	}
+
+	return l
+}
+
+// Log implements log15.Handler.
+func (l *RateLimitHandler) Log(r *log.Record) error {
+	allowed, dropped := l.take(r.Lvl)
+	if !allowed {
+		return nil
+	}
+
+	if dropped > 0 {
+		l.logDropped(r, dropped)
+	}
+
+	return l.handler.Log(r)
+}
+
+// take takes a token from the bucket for the given level, returning true if
+// the record can be logged, along with the number of records that were
+// dropped before this one.
+func (l *RateLimitHandler) take(lvl log.Lvl) (bool, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	bucket, limited := l.buckets[lvl]
+	if !limited {
+		return true, 0
+	}
+
+	if !bucket.take(l.now()) {
+		return false, 0
+	}
+
+	dropped := bucket.dropped
+	bucket.dropped = 0
+
+	return true, dropped
+}
+
+// logDropped passes on a warning that the given number of records at the level
+// of the given record were dropped.
+func (l *RateLimitHandler) logDropped(r *log.Record, dropped int) {
+	l.handler.Log(&log.Record{ //nolint:errcheck
+		Time:     r.Time,
+		Lvl:      log.LvlWarn,
+		Msg:      "log records dropped by rate limit",
+		Ctx:      []interface{}{"droppedlvl", r.Lvl.String(), DroppedKey, dropped},
+		Call:     r.Call,
+		KeyNames: r.KeyNames,
+	})
+}
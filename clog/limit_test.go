@@ -0,0 +1,100 @@
+/*******************************************************************************
+ * Copyright (c) 2026 Genome Research Ltd.
+ *
+ * Author: Sendu Bala <sb10@sanger.ac.uk>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining
+ * a copy of this software and associated documentation files (the
+ * "Software"), to deal in the Software without restriction, including
+ * without limitation the rights to use, copy, modify, merge, publish,
+ * distribute, sublicense, and/or sell copies of the Software, and to
+ * permit persons to whom the Software is furnished to do so, subject to
+ * the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included
+ * in all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ ******************************************************************************/
+
+package clog
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/inconshreveable/log15"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRateLimit(t *testing.T) {
+	background := context.Background()
+
+	Convey("A RateLimitHandler limits records per level", t, func() {
+		ring := NewRingHandler(100)
+		limiter := NewRateLimitHandler(ring, map[string]RateLimit{
+			"debug": {Rate: 1, Burst: 2},
+			"warn":  {Rate: 10, Burst: 1},
+		})
+
+		now := time.Now()
+		limiter.now = func() time.Time { return now }
+
+		ctx := ContextWithLogHandler(background, limiter)
+
+		for i := 0; i < 5; i++ {
+			Debug(ctx, "debug")
+			Warn(ctx, "warn")
+			Error(ctx, "error")
+		}
+
+		So(len(ring.Records(&Query{Lvl: "debug"})), ShouldEqual, 2+1+5)
+		So(len(ring.Records(&Query{Lvl: "warn"})), ShouldEqual, 1+5)
+		So(len(ring.Records(&Query{Lvl: "error"})), ShouldEqual, 5)
+
+		Convey("refilling over time, and reporting how many were dropped", func() {
+			now = now.Add(100 * time.Millisecond)
+			Warn(ctx, "warn")
+			Debug(ctx, "debug")
+
+			records := ring.Records(nil)
+			last := records[len(records)-2:]
+			So(last[0].Msg, ShouldEqual, "log records dropped by rate limit")
+			So(last[0].Lvl, ShouldEqual, log15.LvlWarn)
+			So(last[0].HasField("droppedlvl", "warn"), ShouldBeTrue)
+			So(last[0].HasField(DroppedKey, 4), ShouldBeTrue)
+			So(last[1].Msg, ShouldEqual, "warn")
+
+			now = now.Add(900 * time.Millisecond)
+			Debug(ctx, "debug")
+			Debug(ctx, "debug")
+
+			records = ring.Records(nil)
+			last = records[len(records)-2:]
+			So(last[0].HasField("droppedlvl", "dbug"), ShouldBeTrue)
+			So(last[0].HasField(DroppedKey, 4), ShouldBeTrue)
+			So(last[1].Msg, ShouldEqual, "debug")
+		})
+
+		Convey("never allowing more than the burst at once", func() {
+			now = now.Add(time.Hour)
+			ring = NewRingHandler(100)
+			limiter.handler = ring
+
+			for i := 0; i < 5; i++ {
+				Debug(ctx, "debug")
+			}
+
+			So(msgsOf(ring.Records(nil)), ShouldResemble, []string{
+				"log records dropped by rate limit", "debug", "debug",
+			})
+		})
+	})
+}
@@ -0,0 +1,261 @@
+/*******************************************************************************
+ * Copyright (c) 2026 Genome Research Ltd.
+ *
+ * Author: Sendu Bala <sb10@sanger.ac.uk>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining
+ * a copy of this software and associated documentation files (the
+ * "Software"), to deal in the Software without restriction, including
+ * without limitation the rights to use, copy, modify, merge, publish,
+ * distribute, sublicense, and/or sell copies of the Software, and to
+ * permit persons to whom the Software is furnished to do so, subject to
+ * the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included
+ * in all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ ******************************************************************************/
+
+package clog
+
+// this file implements named loggers, whose levels can be set independently.
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	log "github.com/inconshreveable/log15"
+)
+
+const (
+	// SubsystemKey is the context key that Named() Loggers log their name
+	// under.
+	SubsystemKey = "subsystem"
+
+	// LevelsEnvVar is the environment variable that, if set when this package
+	// is initialised, is passed to SetLevels().
+	LevelsEnvVar = "WR_LOG_LEVELS"
+
+	allSubsystems     = "*"
+	levelsSeparator   = ","
+	levelSeparator    = "="
+	levelSpecNumParts = 2
+)
+
+// LevelSpecError is returned by SetLevels() when given an invalid spec.
+type LevelSpecError string
+
+// Error returns a description of the bad part of the spec.
+func (e LevelSpecError) Error() string {
+	return fmt.Sprintf("bad log level spec [%s]", string(e))
+}
+
+// subsystemLevels stores the levels set for named loggers. It is safe for
+// concurrent use.
+type subsystemLevels struct {
+	mu       sync.RWMutex
+	levels   map[string]log.Lvl
+	fallback log.Lvl
+	hasAll   bool
+}
+
+// levels holds the levels set by SetLevel() and SetLevels().
+var levels = &subsystemLevels{levels: make(map[string]log.Lvl)} //nolint:gochecknoglobals
+
+// set sets the level for the given name, where "*" sets the level for all
+// names that don't have their own level.
+func (s *subsystemLevels) set(name string, lvl log.Lvl) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if name == allSubsystems {
+		s.fallback = lvl
+		s.hasAll = true
+
+		return
+	}
+
+	s.levels[name] = lvl
+}
+
+// reset forgets all levels.
+func (s *subsystemLevels) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.levels = make(map[string]log.Lvl)
+	s.hasAll = false
+}
+
+// get returns the level set for the given name, and whether one was set.
+func (s *subsystemLevels) get(name string) (log.Lvl, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if lvl, found := s.levels[name]; found {
+		return lvl, true
+	}
+
+	return s.fallback, s.hasAll
+}
+
+// maxLvlForRecord returns the level set for the subsystem of the given record,
+// or the given default level if it isn't from a Named() Logger, or its
+// subsystem has no level set.
+func (s *subsystemLevels) maxLvlForRecord(r *log.Record, defaultLvl log.Lvl) log.Lvl {
+	name, found := subsystemOfRecord(r)
+	if !found {
+		return defaultLvl
+	}
+
+	if lvl, set := s.get(name); set {
+		return lvl
+	}
+
+	return defaultLvl
+}
+
+// subsystemOfRecord returns the value of the SubsystemKey in the given
+// record's context, and whether it was there.
+func subsystemOfRecord(r *log.Record) (string, bool) {
+	for i := 0; i < len(r.Ctx)-1; i += 2 {
+		if r.Ctx[i] == SubsystemKey {
+			name, ok := r.Ctx[i+1].(string)
+
+			return name, ok
+		}
+	}
+
+	return "", false
+}
+
+// lvlFilterHandler returns a Handler that only writes records to the given
+// handler that are at the given level or higher, unless the record is from a
+// Named() Logger that has had its own level set, in which case that level is
+// used instead.
+func lvlFilterHandler(maxLvl log.Lvl, h log.Handler) log.Handler {
+	return log.FilterHandler(func(r *log.Record) bool {
+		return r.Lvl <= levels.maxLvlForRecord(r, maxLvl)
+	}, h)
+}
+
+// SetLevel sets the level for Loggers returned by Named() with the given name.
+// The name "*" sets the level for all Named() Loggers that don't have their own
+// level set. Valid lvls are as for ToDefaultAtLevel().
+//
+// These levels take precedence over the level of whatever handlers are in use,
+// and can be changed at any time.
+func SetLevel(name, lvl string) {
+	levels.set(name, lvlFromString(lvl))
+}
+
+// SetLevels takes a comma separated list of name=lvl pairs, eg.
+// "retry=debug,container=info,*=warn", and calls SetLevel() for each of them.
+// Levels previously set are forgotten. An empty spec just forgets all levels.
+func SetLevels(spec string) error {
+	parsed, err := parseLevelSpec(spec)
+	if err != nil {
+		return err
+	}
+
+	levels.reset()
+
+	for name, lvl := range parsed {
+		levels.set(name, lvl)
+	}
+
+	return nil
+}
+
+// parseLevelSpec parses a spec as described in SetLevels() to a map of names
+// to levels.
+func parseLevelSpec(spec string) (map[string]log.Lvl, error) {
+	parsed := make(map[string]log.Lvl)
+
+	for _, pair := range strings.Split(spec, levelsSeparator) {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		parts := strings.Split(pair, levelSeparator)
+		if len(parts) != levelSpecNumParts || parts[0] == "" {
+			return nil, LevelSpecError(pair)
+		}
+
+		lvl, err := log.LvlFromString(parts[1])
+		if err != nil {
+			return nil, LevelSpecError(pair)
+		}
+
+		parsed[parts[0]] = lvl
+	}
+
+	return parsed, nil
+}
+
+// setLevelsFromEnv calls SetLevels() with the value of LevelsEnvVar, logging
+// a warning if it is invalid.
+func setLevelsFromEnv() {
+	if err := SetLevels(os.Getenv(LevelsEnvVar)); err != nil {
+		Warn(context.Background(), "ignoring invalid "+LevelsEnvVar, "err", err)
+	}
+}
+
+// Logger is a named logger, returned by Named().
+type Logger struct {
+	name string
+}
+
+// Named returns a Logger that logs like the package level functions do, but
+// with its name added to the context with key "subsystem". The level of named
+// Loggers can be set independently with SetLevel().
+func Named(name string) *Logger {
+	return &Logger{name: name}
+}
+
+// Name returns the name of this Logger.
+func (l *Logger) Name() string {
+	return l.name
+}
+
+// logger returns the global logger with as much context as possible, including
+// our name.
+func (l *Logger) logger(ctx context.Context) log.Logger {
+	return logger(ctx).New(SubsystemKey, l.name)
+}
+
+// Debug is like the package level Debug(), but includes our name.
+func (l *Logger) Debug(ctx context.Context, msg string, args ...interface{}) {
+	l.logger(ctx).Debug(msg, args...)
+}
+
+// Info is like the package level Info(), but includes our name.
+func (l *Logger) Info(ctx context.Context, msg string, args ...interface{}) {
+	l.logger(ctx).Info(msg, args...)
+}
+
+// Warn is like the package level Warn(), but includes our name.
+func (l *Logger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	l.logger(ctx).Warn(msg, args...)
+}
+
+// Error is like the package level Error(), but includes our name.
+func (l *Logger) Error(ctx context.Context, msg string, args ...interface{}) {
+	l.logger(ctx).Error(msg, args...)
+}
+
+// Crit is like the package level Crit(), but includes our name.
+func (l *Logger) Crit(ctx context.Context, msg string, args ...interface{}) {
+	l.logger(ctx).Crit(msg, args...)
+}
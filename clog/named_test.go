@@ -0,0 +1,161 @@
+/*******************************************************************************
+ * Copyright (c) 2026 Genome Research Ltd.
+ *
+ * Author: Sendu Bala <sb10@sanger.ac.uk>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining
+ * a copy of this software and associated documentation files (the
+ * "Software"), to deal in the Software without restriction, including
+ * without limitation the rights to use, copy, modify, merge, publish,
+ * distribute, sublicense, and/or sell copies of the Software, and to
+ * permit persons to whom the Software is furnished to do so, subject to
+ * the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included
+ * in all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ ******************************************************************************/
+
+package clog
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNamed(t *testing.T) {
+	background := context.Background()
+
+	Convey("Named Loggers log with their name", t, func() {
+		buff := ToBufferAtLevel("debug")
+		defer ToDefault()
+
+		l := Named("retry")
+		So(l.Name(), ShouldEqual, "retry")
+
+		l.Debug(ContextWithJobKey(background, "bar"), "msg", "foo", 1)
+		So(buff.String(), ShouldContainSubstring, "lvl=dbug msg=msg jobkey=bar subsystem=retry foo=1")
+		So(buff.String(), ShouldContainSubstring, "caller=clog/named_test.go")
+
+		checkMethod := func(method func(context.Context, string, ...interface{}), lvl string) {
+			buff.Reset()
+			method(background, "msg")
+			So(buff.String(), ShouldContainSubstring, "lvl="+lvl+" msg=msg subsystem=retry")
+		}
+
+		checkMethod(l.Info, "info")
+		checkMethod(l.Warn, "warn")
+		checkMethod(l.Error, "eror")
+		checkMethod(l.Crit, "crit")
+	})
+
+	Convey("Named Loggers can have their own levels", t, func() {
+		buff := ToBufferAtLevel("warn")
+		defer ToDefault()
+		defer func() { So(SetLevels(""), ShouldBeNil) }()
+
+		retry := Named("retry")
+		container := Named("container")
+		other := Named("other")
+
+		logAll := func() string {
+			buff.Reset()
+			retry.Debug(background, "retry")
+			container.Info(background, "container")
+			other.Info(background, "other")
+			Info(background, "root")
+
+			return buff.String()
+		}
+
+		So(logAll(), ShouldBeBlank)
+
+		SetLevel("retry", "debug")
+		lmsg := logAll()
+		So(lmsg, ShouldContainSubstring, "msg=retry")
+		So(lmsg, ShouldNotContainSubstring, "msg=container")
+		So(lmsg, ShouldNotContainSubstring, "msg=root")
+
+		Convey("which can be changed at runtime, including a fallback for all named loggers", func() {
+			So(SetLevels("container=info, *=info"), ShouldBeNil)
+			lmsg = logAll()
+			So(lmsg, ShouldNotContainSubstring, "msg=retry")
+			So(lmsg, ShouldContainSubstring, "msg=container")
+			So(lmsg, ShouldContainSubstring, "msg=other")
+			So(lmsg, ShouldNotContainSubstring, "msg=root")
+
+			So(SetLevels("*=crit"), ShouldBeNil)
+			buff.Reset()
+			other.Warn(background, "other")
+			Warn(background, "root")
+			So(buff.String(), ShouldNotContainSubstring, "msg=other")
+			So(buff.String(), ShouldContainSubstring, "msg=root")
+		})
+
+		Convey("which also apply to context log handlers", func() {
+			handler := ToBufferAtLevel("warn")
+			ctx := ContextWithLogHandler(background, GetHandler())
+			buff = ToBufferAtLevel("warn")
+			retry.Debug(ctx, "retry")
+			So(handler.String(), ShouldContainSubstring, "msg=retry")
+			So(buff.String(), ShouldBeBlank)
+		})
+
+		Convey("but invalid specs are rejected, leaving levels unchanged", func() {
+			for _, spec := range []string{"retry", "retry=foo", "=debug", "retry=debug=info"} {
+				err := SetLevels(spec)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldStartWith, "bad log level spec")
+			}
+
+			So(logAll(), ShouldContainSubstring, "msg=retry")
+		})
+
+		Convey("and can be used concurrently", func() {
+			wg := &sync.WaitGroup{}
+			wg.Add(2)
+
+			go func() {
+				defer wg.Done()
+				SetLevel("retry", "info")
+			}()
+
+			go func() {
+				defer wg.Done()
+				retry.Debug(background, "retry")
+			}()
+
+			wg.Wait()
+		})
+	})
+
+	Convey("Levels can be set from the environment", t, func() {
+		buff := ToBufferAtLevel("warn")
+		defer ToDefault()
+		defer func() { So(SetLevels(""), ShouldBeNil) }()
+		defer os.Unsetenv(LevelsEnvVar)
+
+		os.Setenv(LevelsEnvVar, "retry=debug")
+		setLevelsFromEnv()
+		Named("retry").Debug(background, "retry")
+		So(buff.String(), ShouldContainSubstring, "msg=retry")
+
+		Convey("with invalid values logging a warning", func() {
+			buff.Reset()
+			os.Setenv(LevelsEnvVar, "retry")
+			setLevelsFromEnv()
+			So(buff.String(), ShouldContainSubstring, "lvl=warn msg=\"ignoring invalid "+LevelsEnvVar+"\"")
+		})
+	})
+}
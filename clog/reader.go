@@ -0,0 +1,376 @@
+/*******************************************************************************
+ * Copyright (c) 2026 Genome Research Ltd.
+ *
+ * Author: Sendu Bala <sb10@sanger.ac.uk>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining
+ * a copy of this software and associated documentation files (the
+ * "Software"), to deal in the Software without restriction, including
+ * without limitation the rights to use, copy, modify, merge, publish,
+ * distribute, sublicense, and/or sell copies of the Software, and to
+ * permit persons to whom the Software is furnished to do so, subject to
+ * the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included
+ * in all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ ******************************************************************************/
+
+package clog
+
+// this file implements parsing and following of log files written by our file
+// handlers.
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hpcloud/tail"
+	log "github.com/inconshreveable/log15"
+)
+
+const (
+	// logfmtTimeFormat is the format log15 uses for the time of logfmt
+	// records; JSON records use RFC3339 with nanoseconds.
+	logfmtTimeFormat = "2006-01-02T15:04:05-0700"
+
+	timeKey = "t"
+	lvlKey  = "lvl"
+	msgKey  = "msg"
+)
+
+// ErrBadLogLine is returned by ParseLine() when a line can't be parsed.
+var ErrBadLogLine = errors.New("could not parse log line")
+
+// ParseLine parses a line written by one of our logfmt or JSON file handlers
+// (eg. CreateFileHandlerAtLevel() or CreateJSONFileHandlerAtLevel()) back into
+// a Record.
+//
+// Values from logfmt lines are strings, while values from JSON lines keep their
+// JSON types, with whole numbers as int64s.
+func ParseLine(line string) (*Record, error) {
+	var (
+		kvs []interface{}
+		err error
+	)
+
+	if strings.HasPrefix(line, "{") {
+		kvs, err = parseJSONLine(line)
+	} else {
+		kvs, err = parseLogfmtLine(line)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return recordFromKeyvals(kvs)
+}
+
+// parseJSONLine parses a JSON object to alternating keys and values.
+func parseJSONLine(line string) ([]interface{}, error) {
+	decoder := json.NewDecoder(strings.NewReader(line))
+	decoder.UseNumber()
+
+	var obj map[string]interface{}
+	if err := decoder.Decode(&obj); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrBadLogLine, err)
+	}
+
+	kvs := make([]interface{}, 0, len(obj)*2) //nolint:mnd
+	for key, val := range obj {
+		kvs = append(kvs, key, jsonNumberToNumber(val))
+	}
+
+	return kvs, nil
+}
+
+// jsonNumberToNumber converts a json.Number to an int64 if it is a whole
+// number, or a float64 otherwise. Other values are returned unaltered.
+func jsonNumberToNumber(val interface{}) interface{} {
+	num, ok := val.(json.Number)
+	if !ok {
+		return val
+	}
+
+	if i, err := num.Int64(); err == nil {
+		return i
+	}
+
+	if f, err := num.Float64(); err == nil {
+		return f
+	}
+
+	return num.String()
+}
+
+// parseLogfmtLine parses a line of logfmt to alternating keys and values.
+func parseLogfmtLine(line string) ([]interface{}, error) {
+	var kvs []interface{}
+
+	for line = strings.TrimSpace(line); line != ""; line = strings.TrimLeft(line, " ") {
+		eq := strings.IndexByte(line, '=')
+		if eq < 1 || strings.ContainsAny(line[:eq], ` "`) {
+			return nil, fmt.Errorf("%w: bad key in [%s]", ErrBadLogLine, line)
+		}
+
+		key := line[:eq]
+
+		val, rest, err := parseLogfmtValue(line[eq+1:])
+		if err != nil {
+			return nil, err
+		}
+
+		kvs = append(kvs, key, val)
+		line = rest
+	}
+
+	return kvs, nil
+}
+
+// parseLogfmtValue parses the logfmt value at the start of s, returning it and
+// the remainder of s.
+func parseLogfmtValue(s string) (string, string, error) {
+	if !strings.HasPrefix(s, `"`) {
+		end := strings.IndexByte(s, ' ')
+		if end == -1 {
+			return s, "", nil
+		}
+
+		return s[:end], s[end:], nil
+	}
+
+	return parseQuotedLogfmtValue(s[1:])
+}
+
+// parseQuotedLogfmtValue parses an escaped value that ends with a quote at the
+// start of s, returning the unescaped value and the remainder of s after the
+// closing quote.
+func parseQuotedLogfmtValue(s string) (string, string, error) {
+	var sb strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			return sb.String(), s[i+1:], nil
+		case '\\':
+			i++
+			if i < len(s) {
+				sb.WriteByte(unescapeLogfmtByte(s[i]))
+			}
+		default:
+			sb.WriteByte(s[i])
+		}
+	}
+
+	return "", "", fmt.Errorf("%w: unterminated quote", ErrBadLogLine)
+}
+
+// unescapeLogfmtByte returns the byte that log15 escaped as \b.
+func unescapeLogfmtByte(b byte) byte {
+	switch b {
+	case 'n':
+		return '\n'
+	case 'r':
+		return '\r'
+	case 't':
+		return '\t'
+	default:
+		return b
+	}
+}
+
+// recordFromKeyvals creates a Record from the given alternating keys and
+// values, which must include the time, level and message.
+func recordFromKeyvals(kvs []interface{}) (*Record, error) {
+	r := &Record{Ctx: make(map[string]interface{}, len(kvs)/2)} //nolint:mnd
+
+	found := 0
+
+	for i := 0; i < len(kvs)-1; i += 2 {
+		key := fieldKey(kvs[i])
+
+		isCore, err := r.setCoreField(key, kvs[i+1])
+		if err != nil {
+			return nil, err
+		}
+
+		if isCore {
+			found++
+
+			continue
+		}
+
+		r.Ctx[key] = kvs[i+1]
+	}
+
+	if found != 3 { //nolint:mnd
+		return nil, fmt.Errorf("%w: missing time, level or message", ErrBadLogLine)
+	}
+
+	r.setCaller()
+
+	return r, nil
+}
+
+// setCoreField sets our Time, Lvl or Msg if key is the corresponding log15
+// key, returning true if so.
+func (r *Record) setCoreField(key string, val interface{}) (bool, error) {
+	str := fmt.Sprint(val)
+
+	var err error
+
+	switch key {
+	case timeKey:
+		r.Time, err = parseLogTime(str)
+	case lvlKey:
+		r.Lvl, err = log.LvlFromString(str)
+	case msgKey:
+		r.Msg = str
+	default:
+		return false, nil
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("%w: %w", ErrBadLogLine, err)
+	}
+
+	return true, nil
+}
+
+// parseLogTime parses a time written in either logfmt or JSON records.
+func parseLogTime(str string) (time.Time, error) {
+	t, err := time.Parse(logfmtTimeFormat, str)
+	if err == nil {
+		return t, nil
+	}
+
+	return time.Parse(time.RFC3339Nano, str)
+}
+
+// ReadLogFile parses every line of the log file at the given path using
+// ParseLine(), and returns the Records that match the given query (nil matches
+// all). Lines that can't be parsed are skipped. There is no limit on the length
+// of lines.
+func ReadLogFile(path string, q *Query) ([]*Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []*Record
+
+	reader := bufio.NewReader(f)
+
+	for {
+		line, err := reader.ReadString('\n')
+		if r := parseAndMatch(strings.TrimRight(line, "\r\n"), q); r != nil {
+			records = append(records, r)
+		}
+
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		} else if err != nil {
+			return records, err
+		}
+	}
+}
+
+// parseAndMatch parses the given line and returns the Record if it matches the
+// given query (nil matches all). Returns nil if the line can't be parsed or
+// doesn't match.
+func parseAndMatch(line string, q *Query) *Record {
+	r, err := ParseLine(line)
+	if err != nil {
+		return nil
+	}
+
+	if q != nil && !q.Matches(r) {
+		return nil
+	}
+
+	return r
+}
+
+// FollowLogFile is like ReadLogFile(), but returns a channel that the matching
+// Records are sent to, first those already in the file, then new ones as they
+// are written. The file doesn't have to exist yet.
+//
+// Following continues when the file is rotated (eg. by a rotating file handler)
+// or truncated, though records may be missed if the file is rotated again
+// immediately after being reopened. The channel is closed when the given
+// context is cancelled.
+func FollowLogFile(ctx context.Context, path string, q *Query) (<-chan *Record, error) {
+	tailer, err := tail.TailFile(path, tail.Config{
+		Follow: true,
+		ReOpen: true,
+		Poll:   true,
+		Logger: tail.DiscardingLogger,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	records := make(chan *Record)
+
+	go followLines(ctx, tailer, q, records)
+
+	return records, nil
+}
+
+// followLines sends Records parsed from the tailer's lines that match the
+// query to the given channel, until the context is cancelled, when the tailer
+// is stopped and the channel closed.
+func followLines(ctx context.Context, tailer *tail.Tail, q *Query, records chan<- *Record) {
+	defer func() {
+		tailer.Stop() //nolint:errcheck
+		tailer.Cleanup()
+		close(records)
+	}()
+
+	for {
+		select {
+		case line, ok := <-tailer.Lines:
+			if !ok {
+				return
+			}
+
+			sendIfMatched(ctx, line, q, records)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// sendIfMatched sends the Record parsed from the given line to the given
+// channel, if it matches the query, unless the context is cancelled first.
+func sendIfMatched(ctx context.Context, line *tail.Line, q *Query, records chan<- *Record) {
+	if line.Err != nil {
+		return
+	}
+
+	r := parseAndMatch(line.Text, q)
+	if r == nil {
+		return
+	}
+
+	select {
+	case records <- r:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,194 @@
+/*******************************************************************************
+ * Copyright (c) 2026 Genome Research Ltd.
+ *
+ * Author: Sendu Bala <sb10@sanger.ac.uk>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining
+ * a copy of this software and associated documentation files (the
+ * "Software"), to deal in the Software without restriction, including
+ * without limitation the rights to use, copy, modify, merge, publish,
+ * distribute, sublicense, and/or sell copies of the Software, and to
+ * permit persons to whom the Software is furnished to do so, subject to
+ * the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included
+ * in all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ ******************************************************************************/
+
+package clog
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hpcloud/tail/watch"
+	log "github.com/inconshreveable/log15"
+	. "github.com/smartystreets/goconvey/convey"
+	ft "github.com/wtsi-ssg/wr/fs/test"
+)
+
+const followTimeout = 5 * time.Second
+
+func TestReader(t *testing.T) {
+	background := context.Background()
+
+	Convey("ParseLine parses logfmt lines", t, func() {
+		r, err := ParseLine(`t=2026-01-02T03:04:05+0000 lvl=warn msg="a \"quoted\" msg" jobkey=abc n=1 ` +
+			`multi="a\nb"`)
+		So(err, ShouldBeNil)
+		So(r.Time.Equal(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)), ShouldBeTrue)
+		So(r.Lvl, ShouldEqual, log.LvlWarn)
+		So(r.Msg, ShouldEqual, `a "quoted" msg`)
+		So(r.Ctx, ShouldResemble, map[string]interface{}{"jobkey": "abc", "n": "1", "multi": "a\nb"})
+
+		Convey("and JSON lines", func() {
+			r, err = ParseLine(`{"t":"2026-01-02T03:04:05.5Z","lvl":"eror","msg":"m","n":1,"f":1.5,"b":true}`)
+			So(err, ShouldBeNil)
+			So(r.Time.Equal(time.Date(2026, 1, 2, 3, 4, 5, 5e8, time.UTC)), ShouldBeTrue)
+			So(r.Lvl, ShouldEqual, log.LvlError)
+			So(r.Msg, ShouldEqual, "m")
+			So(r.Ctx, ShouldResemble, map[string]interface{}{"n": int64(1), "f": 1.5, "b": true})
+		})
+
+		Convey("but not lines that aren't log records", func() {
+			for _, line := range []string{
+				"", "not a log line", `t=2026-01-02T03:04:05+0000 lvl=warn`,
+				`t=2026-01-02T03:04:05+0000 lvl=warn msg="unterminated`,
+				`t=bad lvl=warn msg=m`, `t=2026-01-02T03:04:05+0000 lvl=bad msg=m`, `{"msg":`,
+			} {
+				_, err = ParseLine(line)
+				So(errors.Is(err, ErrBadLogLine), ShouldBeTrue)
+			}
+		})
+	})
+
+	Convey("ReadLogFile reads back records written by our file handlers", t, func() {
+		for _, create := range []func(string, string, ...CallerOption) (log.Handler, error){
+			CreateFileHandlerAtLevel, CreateJSONFileHandlerAtLevel,
+		} {
+			logPath := ft.FilePathInTempDir(t, "clog.log")
+			h, err := create(logPath, "debug")
+			So(err, ShouldBeNil)
+
+			writeTestRecords(h)
+
+			records, err := ReadLogFile(logPath, nil)
+			So(err, ShouldBeNil)
+			So(msgsOf(records), ShouldResemble, []string{"a", "b", "c"})
+			So(records[1].HasField("jobkey", "job2"), ShouldBeTrue)
+			So(records[1].Caller, ShouldNotBeEmpty)
+
+			records, err = ReadLogFile(logPath, &Query{Lvl: "warn", Fields: map[string]interface{}{"jobkey": "job2"}})
+			So(err, ShouldBeNil)
+			So(msgsOf(records), ShouldResemble, []string{"b"})
+		}
+
+		Convey("Even if it has lines longer than 64KiB", func() {
+			logPath := ft.FilePathInTempDir(t, "clog.log")
+			h, err := CreateFileHandlerAtLevel(logPath, "debug")
+			So(err, ShouldBeNil)
+
+			big := strings.Repeat("x", 100*1024)
+			logger := log.New()
+			logger.SetHandler(h)
+			logger.Info("a")
+			logger.Info("b", "big", big)
+			logger.Info("c")
+
+			records, err := ReadLogFile(logPath, nil)
+			So(err, ShouldBeNil)
+			So(msgsOf(records), ShouldResemble, []string{"a", "b", "c"})
+			So(records[1].HasField("big", big), ShouldBeTrue)
+		})
+
+		Convey("Unless the file doesn't exist", func() {
+			_, err := ReadLogFile(ft.FilePathInTempDir(t, "missing.log"), nil)
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("FollowLogFile sends matching records as they are written", t, func() {
+		logPath := ft.FilePathInTempDir(t, "clog.log")
+		ctx, cancel := context.WithCancel(background)
+		defer cancel()
+
+		records, err := FollowLogFile(ctx, logPath, &Query{Fields: map[string]interface{}{"jobkey": "job2"}})
+		So(err, ShouldBeNil)
+
+		h, err := CreateRotatingFileHandlerAtLevel(logPath, "debug", RotateOptions{MaxSize: 300})
+		So(err, ShouldBeNil)
+
+		writeTestRecords(h)
+		So(receiveMsgs(records, 2), ShouldResemble, []string{"b", "c"})
+
+		Convey("surviving rotation", func() {
+			for range 3 {
+				<-time.After(watch.POLL_DURATION)
+				writeTestRecords(h)
+				So(receiveMsgs(records, 2), ShouldResemble, []string{"b", "c"})
+			}
+
+			backups, errg := filepath.Glob(logPath + ".*")
+			So(errg, ShouldBeNil)
+			So(len(backups), ShouldBeGreaterThan, 1)
+
+			cancel()
+			So(receiveMsgs(records, 1), ShouldBeEmpty)
+		})
+
+		Convey("surviving truncation", func() {
+			err = os.Truncate(logPath, 0)
+			So(err, ShouldBeNil)
+			<-time.After(time.Second)
+
+			writeTestRecords(h)
+			So(receiveMsgs(records, 2), ShouldResemble, []string{"b", "c"})
+		})
+	})
+}
+
+// writeTestRecords logs 3 records directly to the given handler, the second
+// of which is at warn level with a jobkey of job2.
+func writeTestRecords(h log.Handler) {
+	logger := log.New()
+	logger.SetHandler(CallerInfoHandler(h))
+	logger.Debug("a", "jobkey", "job1")
+	logger.Warn("b", "jobkey", "job2")
+	logger.Info("c", "jobkey", "job2")
+}
+
+// receiveMsgs returns the messages of up to n records received from the given
+// channel, stopping early if the channel is closed or followTimeout passes.
+func receiveMsgs(records <-chan *Record, n int) []string {
+	var msgs []string
+
+	timeout := time.After(followTimeout)
+
+	for len(msgs) < n {
+		select {
+		case r, ok := <-records:
+			if !ok {
+				return msgs
+			}
+
+			msgs = append(msgs, r.Msg)
+		case <-timeout:
+			return msgs
+		}
+	}
+
+	return msgs
+}
@@ -0,0 +1,132 @@
+/*******************************************************************************
+ * Copyright (c) 2026 Genome Research Ltd.
+ *
+ * Author: Sendu Bala <sb10@sanger.ac.uk>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining
+ * a copy of this software and associated documentation files (the
+ * "Software"), to deal in the Software without restriction, including
+ * without limitation the rights to use, copy, modify, merge, publish,
+ * distribute, sublicense, and/or sell copies of the Software, and to
+ * permit persons to whom the Software is furnished to do so, subject to
+ * the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included
+ * in all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ ******************************************************************************/
+
+package clog
+
+// this file implements a parsed representation of log records.
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/inconshreveable/log15"
+)
+
+// callerKey is the context key that CallerInfoHandler() stores the caller
+// under.
+const callerKey = "caller"
+
+// Record is a log record parsed into its parts, with its context as a map
+// instead of formatted text.
+type Record struct {
+	Time time.Time
+	Lvl  log.Lvl
+	Msg  string
+	Ctx  map[string]interface{}
+
+	// Caller is the file and line number of the calling function, if it was
+	// added to the context by CallerInfoHandler().
+	Caller string
+}
+
+// recordFromLog15 converts a log15 record to a Record. Non-string context keys
+// are converted to strings with fmt.Sprint.
+func recordFromLog15(r *log.Record) *Record {
+	ctx := make(map[string]interface{}, len(r.Ctx)/2) //nolint:mnd
+
+	for i := 0; i < len(r.Ctx)-1; i += 2 {
+		ctx[fieldKey(r.Ctx[i])] = r.Ctx[i+1]
+	}
+
+	record := &Record{Time: r.Time, Lvl: r.Lvl, Msg: r.Msg, Ctx: ctx}
+	record.setCaller()
+
+	return record
+}
+
+// setCaller sets our Caller from our Ctx, if it is there.
+func (r *Record) setCaller() {
+	if caller, ok := r.Ctx[callerKey].(string); ok {
+		r.Caller = caller
+	}
+}
+
+// HasField returns true if our Ctx has the given key, and its value is the
+// same as the given value when both are formatted with fmt.Sprint. That way a
+// retrynum of 3 matches "3".
+func (r *Record) HasField(key string, val interface{}) bool {
+	v, found := r.Ctx[key]
+	if !found {
+		return false
+	}
+
+	return fmt.Sprint(v) == fmt.Sprint(val)
+}
+
+// Query describes which Records you are interested in. The zero value matches
+// all Records.
+type Query struct {
+	// Lvl, if not blank, only matches records at this level or more severe;
+	// eg. "warn" matches "warn", "error" and "crit" records.
+	Lvl string
+
+	// Since, if not zero, only matches records logged at or after this time.
+	Since time.Time
+
+	// Until, if not zero, only matches records logged before this time.
+	Until time.Time
+
+	// Fields only matches records that have all of these context keys and
+	// values, compared as per Record.HasField(); eg. {"jobkey": "abc"}.
+	Fields map[string]interface{}
+}
+
+// Matches returns true if the given Record matches all of our criteria.
+func (q *Query) Matches(r *Record) bool {
+	if q.Lvl != "" && r.Lvl > lvlFromString(q.Lvl) {
+		return false
+	}
+
+	if !q.matchesTime(r.Time) {
+		return false
+	}
+
+	for key, val := range q.Fields {
+		if !r.HasField(key, val) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchesTime returns true if the given time is within our Since and Until.
+func (q *Query) matchesTime(t time.Time) bool {
+	if !q.Since.IsZero() && t.Before(q.Since) {
+		return false
+	}
+
+	return q.Until.IsZero() || t.Before(q.Until)
+}
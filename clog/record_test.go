@@ -0,0 +1,73 @@
+/*******************************************************************************
+ * Copyright (c) 2026 Genome Research Ltd.
+ *
+ * Author: Sendu Bala <sb10@sanger.ac.uk>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining
+ * a copy of this software and associated documentation files (the
+ * "Software"), to deal in the Software without restriction, including
+ * without limitation the rights to use, copy, modify, merge, publish,
+ * distribute, sublicense, and/or sell copies of the Software, and to
+ * permit persons to whom the Software is furnished to do so, subject to
+ * the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included
+ * in all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ ******************************************************************************/
+
+package clog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/inconshreveable/log15"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRecord(t *testing.T) {
+	now := time.Now()
+
+	Convey("log15 records can be converted to Records", t, func() {
+		r := recordFromLog15(&log15.Record{
+			Time: now,
+			Lvl:  log15.LvlWarn,
+			Msg:  "msg",
+			Ctx:  []interface{}{"jobkey", "abc", "retrynum", 3, 1, "one"},
+		})
+
+		So(r.Time, ShouldEqual, now)
+		So(r.Lvl, ShouldEqual, log15.LvlWarn)
+		So(r.Msg, ShouldEqual, "msg")
+		So(r.Ctx, ShouldResemble, map[string]interface{}{"jobkey": "abc", "retrynum": 3, "1": "one"})
+
+		Convey("which can be checked for fields", func() {
+			So(r.HasField("jobkey", "abc"), ShouldBeTrue)
+			So(r.HasField("jobkey", "def"), ShouldBeFalse)
+			So(r.HasField("retrynum", 3), ShouldBeTrue)
+			So(r.HasField("retrynum", "3"), ShouldBeTrue)
+			So(r.HasField("serverid", ""), ShouldBeFalse)
+		})
+
+		Convey("and matched against Querys", func() {
+			So((&Query{}).Matches(r), ShouldBeTrue)
+			So((&Query{Lvl: "debug"}).Matches(r), ShouldBeTrue)
+			So((&Query{Lvl: "warn"}).Matches(r), ShouldBeTrue)
+			So((&Query{Lvl: "error"}).Matches(r), ShouldBeFalse)
+			So((&Query{Since: now}).Matches(r), ShouldBeTrue)
+			So((&Query{Since: now.Add(time.Second)}).Matches(r), ShouldBeFalse)
+			So((&Query{Until: now.Add(time.Second)}).Matches(r), ShouldBeTrue)
+			So((&Query{Until: now}).Matches(r), ShouldBeFalse)
+			So((&Query{Fields: map[string]interface{}{"jobkey": "abc", "retrynum": 3}}).Matches(r), ShouldBeTrue)
+			So((&Query{Fields: map[string]interface{}{"jobkey": "abc", "retrynum": 2}}).Matches(r), ShouldBeFalse)
+		})
+	})
+}
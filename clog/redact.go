@@ -0,0 +1,242 @@
+/*******************************************************************************
+ * Copyright (c) 2026 Genome Research Ltd.
+ *
+ * Author: Sendu Bala <sb10@sanger.ac.uk>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining
+ * a copy of this software and associated documentation files (the
+ * "Software"), to deal in the Software without restriction, including
+ * without limitation the rights to use, copy, modify, merge, publish,
+ * distribute, sublicense, and/or sell copies of the Software, and to
+ * permit persons to whom the Software is furnished to do so, subject to
+ * the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included
+ * in all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ ******************************************************************************/
+
+package clog
+
+// this file implements masking of secrets in log records.
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+
+	log "github.com/inconshreveable/log15"
+)
+
+// RedactedValue is what secrets are replaced with.
+const RedactedValue = "[REDACTED]"
+
+// Redactor describes which secrets to mask in log records. Don't alter a
+// Redactor's Keys after you start using it.
+type Redactor struct {
+	// Keys are context key names whose values are always masked. They match
+	// case-insensitively anywhere in a key, so "token" matches "AuthToken".
+	// Within string values and messages, "key=value" and "key: value" style
+	// text where the key matches also has its value masked.
+	Keys []string
+
+	// Patterns are matched against the message and string and error values.
+	// If a pattern has a capture group, the text of the first group in each
+	// match is masked; otherwise the whole match is masked.
+	Patterns []*regexp.Regexp
+
+	keyPattern *regexp.Regexp
+	once       sync.Once
+}
+
+// DefaultRedactor returns the Redactor that is used by default. It masks keys
+// containing "token", "secret", "password", "passwd" or "apikey", as well as
+// bearer tokens, AWS access key ids, and AWS secret access keys.
+func DefaultRedactor() *Redactor {
+	return &Redactor{
+		Keys: []string{"token", "secret", "password", "passwd", "apikey"},
+		Patterns: []*regexp.Regexp{
+			regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9\-._~+/]+=*)`),
+			regexp.MustCompile(`\b((?:AKIA|ASIA)[0-9A-Z]{16})\b`),
+			regexp.MustCompile(`(?i)aws_secret_access_key\s*[=:]\s*"?([A-Za-z0-9/+=]{40})`),
+		},
+	}
+}
+
+// keyValuePattern returns a regexp that matches "key=value" style text where
+// the key contains one of our Keys, capturing the value.
+func (r *Redactor) keyValuePattern() *regexp.Regexp {
+	r.once.Do(func() {
+		if len(r.Keys) == 0 {
+			return
+		}
+
+		quoted := make([]string, len(r.Keys))
+		for i, key := range r.Keys {
+			quoted[i] = regexp.QuoteMeta(key)
+		}
+
+		r.keyPattern = regexp.MustCompile(`(?i)[\w.-]*(?:` + strings.Join(quoted, "|") +
+			`)[\w.-]*"?\s*[=:]\s*("[^"]*"|[^\s,;&"]+)`)
+	})
+
+	return r.keyPattern
+}
+
+// isSecretKey returns true if the given context key contains one of our Keys.
+func (r *Redactor) isSecretKey(key string) bool {
+	lower := strings.ToLower(key)
+
+	for _, secret := range r.Keys {
+		if strings.Contains(lower, strings.ToLower(secret)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RedactString returns the given string with any secrets in it masked.
+func (r *Redactor) RedactString(s string) string {
+	if kp := r.keyValuePattern(); kp != nil {
+		s = redactMatches(kp, s)
+	}
+
+	for _, pattern := range r.Patterns {
+		s = redactMatches(pattern, s)
+	}
+
+	return s
+}
+
+// redactMatches replaces the first capture group of each match of the pattern
+// in s with RedactedValue, or the whole match if there are no groups.
+func redactMatches(pattern *regexp.Regexp, s string) string {
+	matches := pattern.FindAllStringSubmatchIndex(s, -1)
+	if matches == nil {
+		return s
+	}
+
+	var sb strings.Builder
+
+	last := 0
+
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		if len(m) > 2 && m[2] >= 0 { //nolint:mnd
+			start, end = m[2], m[3]
+		}
+
+		sb.WriteString(s[last:start])
+		sb.WriteString(RedactedValue)
+		last = end
+	}
+
+	sb.WriteString(s[last:])
+
+	return sb.String()
+}
+
+// redactValue returns the given context value with secrets masked. Strings,
+// errors and string slices are checked; other types are returned unaltered.
+// Values are only converted to strings if they contained a secret.
+func (r *Redactor) redactValue(val interface{}) interface{} {
+	switch v := val.(type) {
+	case string:
+		return r.RedactString(v)
+	case error:
+		if redacted := r.RedactString(v.Error()); redacted != v.Error() {
+			return redacted
+		}
+	case []string:
+		redacted := make([]string, len(v))
+		for i, s := range v {
+			redacted[i] = r.RedactString(s)
+		}
+
+		return redacted
+	}
+
+	return val
+}
+
+// Redact returns a copy of the given record with its secrets masked.
+func (r *Redactor) Redact(rec *log.Record) *log.Record {
+	redacted := *rec
+	redacted.Msg = r.RedactString(rec.Msg)
+	redacted.Ctx = make([]interface{}, len(rec.Ctx))
+	copy(redacted.Ctx, rec.Ctx)
+
+	for i := 0; i < len(redacted.Ctx)-1; i += 2 {
+		if r.isSecretKey(fieldKey(redacted.Ctx[i])) {
+			redacted.Ctx[i+1] = RedactedValue
+
+			continue
+		}
+
+		redacted.Ctx[i+1] = r.redactValue(redacted.Ctx[i+1])
+	}
+
+	return &redacted
+}
+
+// RedactHandler returns a Handler that masks secrets in records using the
+// given Redactor, before passing them to the given handler.
+func RedactHandler(r *Redactor, h log.Handler) log.Handler {
+	return log.FuncHandler(func(rec *log.Record) error {
+		return h.Log(r.Redact(rec))
+	})
+}
+
+// redaction holds the Redactor used by the handlers we create.
+type redaction struct {
+	mu       sync.RWMutex
+	redactor *Redactor
+}
+
+// get returns the current Redactor, which may be nil.
+func (rd *redaction) get() *Redactor {
+	rd.mu.RLock()
+	defer rd.mu.RUnlock()
+
+	return rd.redactor
+}
+
+// set sets the current Redactor.
+func (rd *redaction) set(r *Redactor) {
+	rd.mu.Lock()
+	defer rd.mu.Unlock()
+
+	rd.redactor = r
+}
+
+// defaultRedaction holds the Redactor set by SetRedactor().
+var defaultRedaction = &redaction{redactor: DefaultRedactor()} //nolint:gochecknoglobals
+
+// SetRedactor sets the Redactor used by the handlers created by ToDefault(),
+// ToBufferAtLevel(), ToFileAtLevel(), CreateFileHandlerAtLevel() and the other
+// To* and Create* functions, including ones created before this call. nil
+// turns redaction off. Defaults to DefaultRedactor().
+func SetRedactor(r *Redactor) {
+	defaultRedaction.set(r)
+}
+
+// defaultRedactHandler returns a Handler that masks secrets in records using
+// the Redactor set by SetRedactor(), if any, before passing them to the given
+// handler.
+func defaultRedactHandler(h log.Handler) log.Handler {
+	return log.FuncHandler(func(rec *log.Record) error {
+		if r := defaultRedaction.get(); r != nil {
+			rec = r.Redact(rec)
+		}
+
+		return h.Log(rec)
+	})
+}
@@ -0,0 +1,130 @@
+/*******************************************************************************
+ * Copyright (c) 2026 Genome Research Ltd.
+ *
+ * Author: Sendu Bala <sb10@sanger.ac.uk>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining
+ * a copy of this software and associated documentation files (the
+ * "Software"), to deal in the Software without restriction, including
+ * without limitation the rights to use, copy, modify, merge, publish,
+ * distribute, sublicense, and/or sell copies of the Software, and to
+ * permit persons to whom the Software is furnished to do so, subject to
+ * the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included
+ * in all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ ******************************************************************************/
+
+package clog
+
+// this file implements an in-memory handler of the most recent log records.
+
+import (
+	"sync"
+
+	log "github.com/inconshreveable/log15"
+)
+
+// RingHandler is a log15 handler that keeps the most recent records in memory
+// as Records, for later querying. Make one with NewRingHandler(). It is safe
+// for concurrent use.
+type RingHandler struct {
+	mu      sync.RWMutex
+	records []*Record
+	next    int
+	full    bool
+}
+
+// NewRingHandler returns a RingHandler that keeps the last size records. size
+// less than 1 is treated as 1.
+//
+// You can use it like any other handler, eg. with AddHandler() or
+// ContextWithLogHandler(), or use ToRingAtLevel().
+func NewRingHandler(size int) *RingHandler {
+	if size < 1 {
+		size = 1
+	}
+
+	return &RingHandler{records: make([]*Record, size)}
+}
+
+// Log implements log15.Handler, storing the given record, replacing the oldest
+// one if we're full.
+func (h *RingHandler) Log(r *log.Record) error {
+	record := recordFromLog15(r)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.records[h.next] = record
+	h.next++
+
+	if h.next == len(h.records) {
+		h.next = 0
+		h.full = true
+	}
+
+	return nil
+}
+
+// Records returns the stored records that match the given query, oldest first.
+// A nil query matches all records.
+func (h *RingHandler) Records(q *Query) []*Record {
+	if q == nil {
+		q = &Query{}
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var matches []*Record
+
+	for _, r := range h.ordered() {
+		if q.Matches(r) {
+			matches = append(matches, r)
+		}
+	}
+
+	return matches
+}
+
+// ordered returns our records, oldest first. You must hold the lock.
+func (h *RingHandler) ordered() []*Record {
+	if !h.full {
+		return h.records[:h.next]
+	}
+
+	ordered := make([]*Record, 0, len(h.records))
+	ordered = append(ordered, h.records[h.next:]...)
+
+	return append(ordered, h.records[:h.next]...)
+}
+
+// Len returns the number of records currently stored.
+func (h *RingHandler) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if h.full {
+		return len(h.records)
+	}
+
+	return h.next
+}
+
+// ToRingAtLevel sets the global logger to log to the returned RingHandler at
+// the given level, keeping the last size records.
+func ToRingAtLevel(size int, lvl string) *RingHandler {
+	ring := NewRingHandler(size)
+	toOutputAtLevel(ring, lvlFromString(lvl))
+
+	return ring
+}
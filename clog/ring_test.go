@@ -0,0 +1,142 @@
+/*******************************************************************************
+ * Copyright (c) 2026 Genome Research Ltd.
+ *
+ * Author: Sendu Bala <sb10@sanger.ac.uk>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining
+ * a copy of this software and associated documentation files (the
+ * "Software"), to deal in the Software without restriction, including
+ * without limitation the rights to use, copy, modify, merge, publish,
+ * distribute, sublicense, and/or sell copies of the Software, and to
+ * permit persons to whom the Software is furnished to do so, subject to
+ * the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included
+ * in all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ ******************************************************************************/
+
+package clog
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/inconshreveable/log15"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRing(t *testing.T) {
+	background := context.Background()
+
+	Convey("A RingHandler keeps the last N records", t, func() {
+		ring := ToRingAtLevel(3, "debug")
+		defer ToDefault()
+
+		So(ring.Len(), ShouldEqual, 0)
+		So(ring.Records(nil), ShouldBeEmpty)
+
+		Debug(background, "0")
+		Info(background, "1")
+		So(ring.Len(), ShouldEqual, 2)
+		So(msgsOf(ring.Records(nil)), ShouldResemble, []string{"0", "1"})
+
+		Warn(background, "2")
+		Error(background, "3")
+		So(ring.Len(), ShouldEqual, 3)
+		So(msgsOf(ring.Records(nil)), ShouldResemble, []string{"1", "2", "3"})
+
+		Convey("as parsed records including caller info", func() {
+			records := ring.Records(nil)
+			So(records[0].Lvl, ShouldEqual, log15.LvlInfo)
+			So(records[1].Ctx["caller"], ShouldStartWith, "clog/ring_test.go:")
+			So(records[1].Caller, ShouldEqual, records[1].Ctx["caller"])
+			So(records[0].Caller, ShouldBeBlank)
+		})
+
+		Convey("which can be queried by level", func() {
+			So(msgsOf(ring.Records(&Query{Lvl: "warn"})), ShouldResemble, []string{"2", "3"})
+			So(msgsOf(ring.Records(&Query{Lvl: "error"})), ShouldResemble, []string{"3"})
+		})
+
+		Convey("which can be queried by time window", func() {
+			records := ring.Records(nil)
+			mid := records[1].Time
+
+			So(len(ring.Records(&Query{Since: mid})), ShouldBeGreaterThanOrEqualTo, 2)
+			So(ring.Records(&Query{Since: time.Now().Add(time.Second)}), ShouldBeEmpty)
+			So(ring.Records(&Query{Until: records[0].Time}), ShouldBeEmpty)
+		})
+
+		Convey("which can be queried by context field", func() {
+			jobCtx := ContextWithJobKey(ContextForRetries(background, "doing foo"), "abc")
+			Warn(jobCtx, "4")
+			Warn(ContextWithJobKey(background, "def"), "5")
+			Debug(jobCtx, "6")
+
+			So(msgsOf(ring.Records(&Query{Fields: map[string]interface{}{"jobkey": "abc"}})),
+				ShouldResemble, []string{"4", "6"})
+			So(msgsOf(ring.Records(&Query{Lvl: "warn", Fields: map[string]interface{}{"jobkey": "abc"}})),
+				ShouldResemble, []string{"4"})
+
+			records := ring.Records(&Query{Fields: map[string]interface{}{"jobkey": "abc"}})
+			retryset := records[0].Ctx["retryset"]
+			So(retryset, ShouldNotBeEmpty)
+			So(msgsOf(ring.Records(&Query{Fields: map[string]interface{}{"retryset": retryset}})),
+				ShouldResemble, []string{"4", "6"})
+		})
+
+		Convey("but not below its level", func() {
+			ring = ToRingAtLevel(3, "warn")
+			Info(background, "info")
+			So(ring.Len(), ShouldEqual, 0)
+		})
+	})
+
+	Convey("A RingHandler must have a size of at least 1", t, func() {
+		ring := NewRingHandler(0)
+		ctx := ContextWithLogHandler(background, ring)
+		Info(ctx, "0")
+		Info(ctx, "1")
+		So(msgsOf(ring.Records(nil)), ShouldResemble, []string{"1"})
+	})
+
+	Convey("A RingHandler can be used concurrently", t, func() {
+		ring := NewRingHandler(10)
+		ctx := ContextWithLogHandler(background, ring)
+		wg := &sync.WaitGroup{}
+		n := 100
+
+		wg.Add(n)
+		for i := 0; i < n; i++ {
+			go func(i int) {
+				defer wg.Done()
+				Info(ctx, strconv.Itoa(i))
+				ring.Records(&Query{Lvl: "info"})
+			}(i)
+		}
+		wg.Wait()
+
+		So(ring.Len(), ShouldEqual, 10)
+	})
+}
+
+// msgsOf returns the Msg of each of the given records.
+func msgsOf(records []*Record) []string {
+	msgs := make([]string, len(records))
+	for i, r := range records {
+		msgs[i] = r.Msg
+	}
+
+	return msgs
+}
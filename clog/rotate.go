@@ -0,0 +1,354 @@
+/*******************************************************************************
+ * Copyright (c) 2026 Genome Research Ltd.
+ *
+ * Author: Sendu Bala <sb10@sanger.ac.uk>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining
+ * a copy of this software and associated documentation files (the
+ * "Software"), to deal in the Software without restriction, including
+ * without limitation the rights to use, copy, modify, merge, publish,
+ * distribute, sublicense, and/or sell copies of the Software, and to
+ * permit persons to whom the Software is furnished to do so, subject to
+ * the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included
+ * in all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ ******************************************************************************/
+
+package clog
+
+// this file implements a file handler that rotates its log file.
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/inconshreveable/log15"
+)
+
+const (
+	rotatedTimeFormat = "20060102T150405.000000000"
+	gzipSuffix        = ".gz"
+	logFilePerms      = 0644
+)
+
+// RotateOptions determine when a rotating file handler rotates its log file,
+// and what happens to the rotated files.
+type RotateOptions struct {
+	// MaxSize is the size in bytes the log file can reach before it is
+	// rotated. 0 means there is no size limit.
+	MaxSize int64
+
+	// MaxAge is how long the log file can be written to before it is rotated.
+	// 0 means there is no age limit. Age is measured from when the file was
+	// last rotated, or for an existing file (eg. after a restart), from the
+	// time of its first record. If that can't be parsed with ParseLine(), the
+	// file's modification time is used instead.
+	MaxAge time.Duration
+
+	// MaxBackups is the number of rotated files to retain; older ones are
+	// deleted. 0 means all rotated files are retained.
+	MaxBackups int
+
+	// Compress, if true, gzips rotated files.
+	Compress bool
+
+	// Format is how records are formatted. Defaults to log15.LogfmtFormat().
+	Format log.Format
+}
+
+// format returns our Format, defaulting to logfmt.
+func (o RotateOptions) format() log.Format {
+	if o.Format == nil {
+		return log.LogfmtFormat()
+	}
+
+	return o.Format
+}
+
+// rotatingWriter is an io.Writer that writes to a file, rotating it according
+// to some RotateOptions. It is safe for concurrent use.
+type rotatingWriter struct {
+	path   string
+	opts   RotateOptions
+	now    func() time.Time
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+}
+
+// newRotatingWriter returns a rotatingWriter that has opened the file at the
+// given path for appending.
+func newRotatingWriter(path string, opts RotateOptions) (*rotatingWriter, error) {
+	w := &rotatingWriter{path: path, opts: opts, now: time.Now}
+
+	return w, w.open()
+}
+
+// open opens our path for appending, noting its current size and when it
+// started being written to.
+func (w *rotatingWriter) open() error {
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, logFilePerms)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+
+		return err
+	}
+
+	w.file = f
+	w.size = info.Size()
+	w.opened = w.now()
+
+	if w.size > 0 {
+		w.opened = firstRecordTime(w.path, info.ModTime())
+	}
+
+	return nil
+}
+
+// firstRecordTime returns the time of the first record in the log file at the
+// given path, or the given fallback if it can't be read or parsed.
+func firstRecordTime(path string, fallback time.Time) time.Time {
+	f, err := os.Open(path)
+	if err != nil {
+		return fallback
+	}
+	defer f.Close()
+
+	line, err := bufio.NewReader(f).ReadString('\n')
+	if err != nil {
+		return fallback
+	}
+
+	r, err := ParseLine(strings.TrimRight(line, "\r\n"))
+	if err != nil {
+		return fallback
+	}
+
+	return r.Time
+}
+
+// Write writes p to our file, first rotating it if writing p would make it too
+// big, or if it has become too old.
+func (w *rotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.shouldRotate(len(p)) {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+
+	return n, err
+}
+
+// shouldRotate returns true if the file is not empty and writing the given
+// number of bytes would exceed MaxSize, or the file is older than MaxAge.
+func (w *rotatingWriter) shouldRotate(bytes int) bool {
+	if w.size == 0 {
+		return false
+	}
+
+	if w.opts.MaxSize > 0 && w.size+int64(bytes) > w.opts.MaxSize {
+		return true
+	}
+
+	return w.opts.MaxAge > 0 && w.now().Sub(w.opened) >= w.opts.MaxAge
+}
+
+// rotate closes our file, archives it, and opens a new file. The file is
+// reopened even if closing or archiving fails, so that we can keep writing.
+func (w *rotatingWriter) rotate() error {
+	err := w.file.Close()
+	if err == nil {
+		err = w.archive()
+	}
+
+	return errors.Join(err, w.open())
+}
+
+// archive renames our closed file with a timestamp suffix, optionally
+// compresses it, and deletes excess rotated files.
+func (w *rotatingWriter) archive() error {
+	rotated := w.path + "." + w.now().Format(rotatedTimeFormat)
+	if err := os.Rename(w.path, rotated); err != nil {
+		return err
+	}
+
+	if w.opts.Compress {
+		if err := gzipFile(rotated); err != nil {
+			return err
+		}
+	}
+
+	return w.removeExcessBackups()
+}
+
+// gzipFile compresses the file at the given path to path.gz, then deletes the
+// original.
+func gzipFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(path+gzipSuffix, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, logFilePerms)
+	if err != nil {
+		return err
+	}
+
+	if err = copyCompressed(out, in); err != nil {
+		out.Close()
+
+		return err
+	}
+
+	if err = out.Close(); err != nil {
+		return err
+	}
+
+	return os.Remove(path)
+}
+
+// copyCompressed gzips the contents of in to out.
+func copyCompressed(out io.Writer, in io.Reader) error {
+	gz := gzip.NewWriter(out)
+
+	if _, err := io.Copy(gz, in); err != nil {
+		return err
+	}
+
+	return gz.Close()
+}
+
+// removeExcessBackups deletes the oldest rotated files, so that no more than
+// MaxBackups remain.
+func (w *rotatingWriter) removeExcessBackups() error {
+	if w.opts.MaxBackups <= 0 {
+		return nil
+	}
+
+	backups, err := w.backups()
+	if err != nil {
+		return err
+	}
+
+	for len(backups) > w.opts.MaxBackups {
+		if err = os.Remove(backups[0]); err != nil {
+			return err
+		}
+
+		backups = backups[1:]
+	}
+
+	return nil
+}
+
+// backups returns the paths of our rotated files, oldest first. Other files
+// that merely start with our path, like path.lock, are ignored.
+func (w *rotatingWriter) backups() ([]string, error) {
+	matches, err := filepath.Glob(escapeGlob(w.path) + ".*")
+	if err != nil {
+		return nil, err
+	}
+
+	backups := make([]string, 0, len(matches))
+
+	for _, match := range matches {
+		if w.isBackup(match) {
+			backups = append(backups, match)
+		}
+	}
+
+	sort.Strings(backups)
+
+	return backups, nil
+}
+
+// isBackup returns true if the given path is one that rotate() would have
+// created: our path with a timestamp suffix, optionally gzipped.
+func (w *rotatingWriter) isBackup(path string) bool {
+	suffix := strings.TrimSuffix(strings.TrimPrefix(path, w.path+"."), gzipSuffix)
+
+	_, err := time.Parse(rotatedTimeFormat, suffix)
+
+	return err == nil
+}
+
+// escapeGlob escapes the glob meta characters in the given path.
+func escapeGlob(path string) string {
+	escaped := make([]rune, 0, len(path))
+
+	for _, r := range path {
+		switch r {
+		case '*', '?', '[', '\\':
+			escaped = append(escaped, '\\')
+		}
+
+		escaped = append(escaped, r)
+	}
+
+	return string(escaped)
+}
+
+// CreateRotatingFileHandlerAtLevel returns a log15 file handler at the given
+// level that rotates the file at the given path according to the given
+// options. Caller info is added as per CallerInfoHandler() with the given
+// caller options.
+func CreateRotatingFileHandlerAtLevel(path, lvl string, opts RotateOptions,
+	callerOpts ...CallerOption) (log.Handler, error) {
+	w, err := newRotatingWriter(path, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return createFilteredInfoHandler(log.StreamHandler(w, opts.format()), lvlFromString(lvl), callerOpts...), nil
+}
+
+// ContextWithRotatingFileHandler returns a context that will log to the given
+// file at the given level, rotating the file according to the given options,
+// and adding caller info as per the given caller options.
+func ContextWithRotatingFileHandler(ctx context.Context, path, lvl string,
+	opts RotateOptions, callerOpts ...CallerOption) (context.Context, error) {
+	fh, err := CreateRotatingFileHandlerAtLevel(path, lvl, opts, callerOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return ContextWithLogHandler(ctx, fh), nil
+}
+
+// ToRotatingFileAtLevel sets the global logger to log to a file at the given
+// path and at the given level, rotating the file according to the given
+// options, and adding caller info as per the given caller options.
+func ToRotatingFileAtLevel(path, lvl string, opts RotateOptions, callerOpts ...CallerOption) error {
+	return setRootHandlerIfNoError(CreateRotatingFileHandlerAtLevel(path, lvl, opts, callerOpts...))
+}
@@ -0,0 +1,300 @@
+/*******************************************************************************
+ * Copyright (c) 2026 Genome Research Ltd.
+ *
+ * Author: Sendu Bala <sb10@sanger.ac.uk>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining
+ * a copy of this software and associated documentation files (the
+ * "Software"), to deal in the Software without restriction, including
+ * without limitation the rights to use, copy, modify, merge, publish,
+ * distribute, sublicense, and/or sell copies of the Software, and to
+ * permit persons to whom the Software is furnished to do so, subject to
+ * the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included
+ * in all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ ******************************************************************************/
+
+package clog
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/inconshreveable/log15"
+	. "github.com/smartystreets/goconvey/convey"
+	fl "github.com/wtsi-ssg/wr/fs/file"
+	ft "github.com/wtsi-ssg/wr/fs/test"
+)
+
+func TestRotate(t *testing.T) {
+	background := context.Background()
+	line := []byte("0123456789\n")
+
+	Convey("A rotatingWriter rotates when its file would exceed MaxSize", t, func() {
+		logPath := ft.FilePathInTempDir(t, "clog.log")
+		w, err := newRotatingWriter(logPath, RotateOptions{MaxSize: 25})
+		So(err, ShouldBeNil)
+
+		for i := 0; i < 5; i++ {
+			_, err = w.Write(line)
+			So(err, ShouldBeNil)
+		}
+
+		backups, err := w.backups()
+		So(err, ShouldBeNil)
+		So(len(backups), ShouldEqual, 2)
+
+		for _, backup := range backups {
+			So(fileSize(backup), ShouldEqual, 2*len(line))
+		}
+
+		So(fileSize(logPath), ShouldEqual, len(line))
+
+		Convey("A single write larger than MaxSize goes to a fresh file", func() {
+			big := []byte(strings.Repeat("a", 30) + "\n")
+			_, err = w.Write(big)
+			So(err, ShouldBeNil)
+			So(fileSize(logPath), ShouldEqual, len(big))
+		})
+	})
+
+	Convey("A rotatingWriter rotates when its file is older than MaxAge", t, func() {
+		logPath := ft.FilePathInTempDir(t, "clog.log")
+		now := time.Now()
+		w, err := newRotatingWriter(logPath, RotateOptions{MaxAge: time.Hour})
+		So(err, ShouldBeNil)
+
+		w.now = func() time.Time { return now }
+		w.opened = now
+
+		_, err = w.Write(line)
+		So(err, ShouldBeNil)
+
+		now = now.Add(59 * time.Minute)
+		_, err = w.Write(line)
+		So(err, ShouldBeNil)
+
+		backups, err := w.backups()
+		So(err, ShouldBeNil)
+		So(len(backups), ShouldEqual, 0)
+
+		now = now.Add(1 * time.Minute)
+		_, err = w.Write(line)
+		So(err, ShouldBeNil)
+
+		backups, err = w.backups()
+		So(err, ShouldBeNil)
+		So(len(backups), ShouldEqual, 1)
+		So(fileSize(backups[0]), ShouldEqual, 2*len(line))
+		So(fileSize(logPath), ShouldEqual, len(line))
+	})
+
+	Convey("A rotatingWriter measures the age of an existing file from its first record", t, func() {
+		logPath := ft.FilePathInTempDir(t, "clog.log")
+		firstTime := time.Now().Add(-2 * time.Hour).Truncate(time.Second)
+		first := "t=" + firstTime.Format(logfmtTimeFormat) + " lvl=info msg=a\n"
+
+		err := os.WriteFile(logPath, []byte(first), logFilePerms)
+		So(err, ShouldBeNil)
+
+		w, err := newRotatingWriter(logPath, RotateOptions{MaxAge: time.Hour})
+		So(err, ShouldBeNil)
+		So(w.opened, ShouldEqual, firstTime)
+
+		_, err = w.Write(line)
+		So(err, ShouldBeNil)
+
+		backups, err := w.backups()
+		So(err, ShouldBeNil)
+		So(len(backups), ShouldEqual, 1)
+		So(fileSize(logPath), ShouldEqual, len(line))
+
+		Convey("or its modification time if that can't be parsed", func() {
+			mtime := time.Now().Add(-3 * time.Hour).Truncate(time.Second)
+			err = os.WriteFile(logPath, []byte("not a record\n"), logFilePerms)
+			So(err, ShouldBeNil)
+			err = os.Chtimes(logPath, mtime, mtime)
+			So(err, ShouldBeNil)
+
+			w, err = newRotatingWriter(logPath, RotateOptions{MaxAge: time.Hour})
+			So(err, ShouldBeNil)
+			So(w.opened.Equal(mtime), ShouldBeTrue)
+		})
+	})
+
+	Convey("A rotatingWriter only retains MaxBackups rotated files, optionally compressed", t, func() {
+		logPath := ft.FilePathInTempDir(t, "clog.log")
+		w, err := newRotatingWriter(logPath, RotateOptions{MaxSize: 1, MaxBackups: 2, Compress: true})
+		So(err, ShouldBeNil)
+
+		for i := 0; i < 5; i++ {
+			_, err = w.Write([]byte{byte('a' + i), '\n'})
+			So(err, ShouldBeNil)
+		}
+
+		backups, err := w.backups()
+		So(err, ShouldBeNil)
+		So(len(backups), ShouldEqual, 2)
+		So(backups[0], ShouldEndWith, gzipSuffix)
+		So(gunzipFile(backups[0]), ShouldEqual, "c\n")
+		So(gunzipFile(backups[1]), ShouldEqual, "d\n")
+
+		content, err := fl.ToString(logPath)
+		So(err, ShouldBeNil)
+		So(content, ShouldEqual, "e\n")
+
+		Convey("without deleting other files that start with the log path", func() {
+			for _, suffix := range []string{".lock", ".bak", ".20060102"} {
+				err = os.WriteFile(logPath+suffix, []byte("x"), logFilePerms)
+				So(err, ShouldBeNil)
+			}
+
+			for i := 0; i < 3; i++ {
+				_, err = w.Write([]byte{'f', '\n'})
+				So(err, ShouldBeNil)
+			}
+
+			backups, err = w.backups()
+			So(err, ShouldBeNil)
+			So(len(backups), ShouldEqual, 2)
+
+			for _, suffix := range []string{".lock", ".bak", ".20060102"} {
+				_, err = os.Stat(logPath + suffix)
+				So(err, ShouldBeNil)
+			}
+		})
+	})
+
+	Convey("A rotatingWriter keeps working if rotation fails", t, func() {
+		logPath := ft.FilePathInTempDir(t, "clog.log")
+		now := time.Now()
+		w, err := newRotatingWriter(logPath, RotateOptions{MaxSize: 1})
+		So(err, ShouldBeNil)
+
+		w.now = func() time.Time { return now }
+
+		_, err = w.Write(line)
+		So(err, ShouldBeNil)
+
+		blocker := logPath + "." + now.Format(rotatedTimeFormat)
+		err = os.MkdirAll(filepath.Join(blocker, "subdir"), 0700)
+		So(err, ShouldBeNil)
+
+		_, err = w.Write(line)
+		So(err, ShouldNotBeNil)
+
+		now = now.Add(time.Second)
+		_, err = w.Write(line)
+		So(err, ShouldBeNil)
+		So(fileSize(logPath), ShouldEqual, len(line))
+		So(fileSize(logPath+"."+now.Format(rotatedTimeFormat)), ShouldEqual, len(line))
+	})
+
+	Convey("You can log to a rotating file at a desired level", t, func() {
+		logPath := ft.FilePathInTempDir(t, "clog.log")
+		err := ToRotatingFileAtLevel(logPath, "warn", RotateOptions{MaxSize: 100})
+		So(err, ShouldBeNil)
+		defer ToDefault()
+
+		Warn(background, "msg", "foo", 1)
+		Debug(background, "msg", "debug", 1)
+		Warn(background, "msg", "foo", 2)
+
+		matches, err := filepath.Glob(logPath + "*")
+		So(err, ShouldBeNil)
+		So(len(matches), ShouldEqual, 2)
+
+		content, err := fl.ToString(logPath)
+		So(err, ShouldBeNil)
+		So(content, ShouldContainSubstring, "foo=2")
+		So(content, ShouldContainSubstring, "caller=clog/rotate_test.go")
+		So(content, ShouldNotContainSubstring, "debug=1")
+
+		Convey("Unless the path is invalid", func() {
+			So(ToRotatingFileAtLevel("", "warn", RotateOptions{}), ShouldNotBeNil)
+		})
+	})
+
+	Convey("Context with a rotating file handler logs JSON to a given file", t, func() {
+		logPath := ft.FilePathInTempDir(t, "job.log")
+		ctxf, err := ContextWithRotatingFileHandler(ContextWithJobKey(background, "bar"), logPath, "debug",
+			RotateOptions{MaxSize: 1000, MaxBackups: 1, Format: log15.JsonFormat()})
+		So(err, ShouldBeNil)
+
+		Debug(ctxf, "msg", "foo", 1)
+		records := decodeJSONFile(logPath)
+		So(len(records), ShouldEqual, 1)
+		So(records[0]["jobkey"], ShouldEqual, "bar")
+
+		Convey("and rotates it safely under concurrent logging", func() {
+			wg := &sync.WaitGroup{}
+			n := 100
+
+			wg.Add(n)
+			for i := 0; i < n; i++ {
+				go func(i int) {
+					defer wg.Done()
+					Debug(ctxf, "msg", "i", i)
+				}(i)
+			}
+			wg.Wait()
+
+			matches, err := filepath.Glob(logPath + "*")
+			So(err, ShouldBeNil)
+			So(len(matches), ShouldEqual, 2)
+
+			for _, path := range matches {
+				for _, record := range decodeJSONFile(path) {
+					So(record["jobkey"], ShouldEqual, "bar")
+				}
+			}
+		})
+
+		Convey("Unless the path is invalid", func() {
+			ctxf, err = ContextWithRotatingFileHandler(background, "", "debug", RotateOptions{})
+			So(ctxf, ShouldBeNil)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+// fileSize returns the size of the file at the given path.
+func fileSize(path string) int {
+	info, err := os.Stat(path)
+	So(err, ShouldBeNil)
+
+	return int(info.Size())
+}
+
+// gunzipFile returns the decompressed contents of the gzipped file at the given
+// path.
+func gunzipFile(path string) string {
+	f, err := os.Open(path)
+	So(err, ShouldBeNil)
+
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	So(err, ShouldBeNil)
+
+	content, err := io.ReadAll(gz)
+	So(err, ShouldBeNil)
+
+	return string(content)
+}
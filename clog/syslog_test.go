@@ -0,0 +1,131 @@
+/*******************************************************************************
+ * Copyright (c) 2026 Genome Research Ltd.
+ *
+ * Author: Sendu Bala <sb10@sanger.ac.uk>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining
+ * a copy of this software and associated documentation files (the
+ * "Software"), to deal in the Software without restriction, including
+ * without limitation the rights to use, copy, modify, merge, publish,
+ * distribute, sublicense, and/or sell copies of the Software, and to
+ * permit persons to whom the Software is furnished to do so, subject to
+ * the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included
+ * in all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ ******************************************************************************/
+
+package clog
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/syslog"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	ft "github.com/wtsi-ssg/wr/fs/test"
+)
+
+func TestSyslog(t *testing.T) {
+	background := context.Background()
+
+	Convey("syslogParamName makes valid structured data parameter names", t, func() {
+		So(syslogParamName("jobkey"), ShouldEqual, "jobkey")
+		So(syslogParamName(`a b=c]d"e`), ShouldEqual, "a_b_c_d_e")
+		So(syslogParamName(""), ShouldEqual, "_")
+		So(len(syslogParamName(strings.Repeat("a", 100))), ShouldEqual, maxSyslogParamLength)
+	})
+
+	Convey("Given a SyslogHandler sending to a stand-in syslog socket", t, func() {
+		conn, path := listenUnixgram(t)
+
+		sh, err := NewSyslogHandler(SyslogOptions{
+			Network:  "unixgram",
+			Address:  path,
+			Facility: syslog.LOG_DAEMON,
+			AppName:  "wrtest",
+			Hostname: "host1",
+		})
+		So(err, ShouldBeNil)
+		defer sh.Close()
+
+		ToHandlerAtLevel(sh, "debug")
+		defer ToDefault()
+
+		Convey("records are sent in RFC 5424 format with context as structured data", func() {
+			ctx := ContextWithSchedulerType(ContextWithServerID(ContextWithJobKey(background, "job1"), "server1"), "lsf")
+			Warn(ctx, "a msg", "weird", `q"b\]`)
+
+			msg := string(readDatagram(conn))
+			prefix := fmt.Sprintf("<%d>1 ", int(syslog.LOG_DAEMON)+4)
+			So(msg, ShouldStartWith, prefix)
+
+			parts := strings.SplitN(strings.TrimPrefix(msg, prefix), " ", 6)
+			So(len(parts), ShouldEqual, 6)
+
+			ts, err := time.Parse(time.RFC3339Nano, parts[0])
+			So(err, ShouldBeNil)
+			So(ts, ShouldHappenWithin, time.Minute, time.Now())
+			So(parts[1:5], ShouldResemble, []string{"host1", "wrtest", fmt.Sprint(os.Getpid()), "-"})
+			So(parts[5], ShouldStartWith, `[wr@32473 jobkey="job1" serverid="server1" schedulertype="lsf" weird="q\"b\\\]" `+
+				`caller="clog/syslog_test.go:`)
+			So(parts[5], ShouldEndWith, `"] a msg`)
+
+			Info(background, "no context")
+			So(string(readDatagram(conn)), ShouldEndWith, " - - no context")
+		})
+	})
+
+	Convey("A SyslogHandler uses octet counting over stream connections", t, func() {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		defer listener.Close()
+
+		sh, err := NewSyslogHandler(SyslogOptions{Network: "tcp", Address: listener.Addr().String(), SDID: "x@1"})
+		So(err, ShouldBeNil)
+		defer sh.Close()
+
+		server, err := listener.Accept()
+		So(err, ShouldBeNil)
+		defer server.Close()
+
+		ToHandlerAtLevel(sh, "debug")
+		defer ToDefault()
+
+		Info(ContextWithJobKey(background, "job1"), "msg")
+
+		reader := bufio.NewReader(server)
+		length, err := reader.ReadString(' ')
+		So(err, ShouldBeNil)
+
+		var n int
+		_, err = fmt.Sscanf(length, "%d ", &n)
+		So(err, ShouldBeNil)
+
+		buf := make([]byte, n)
+		_, err = io.ReadFull(reader, buf)
+		So(err, ShouldBeNil)
+		So(string(buf), ShouldStartWith, fmt.Sprintf("<%d>1 ", int(syslog.LOG_USER)+6))
+		So(string(buf), ShouldEndWith, ` [x@1 jobkey="job1"] msg`)
+	})
+
+	Convey("NewSyslogHandler fails if it can't connect", t, func() {
+		_, err := NewSyslogHandler(SyslogOptions{Network: "unixgram", Address: ft.FilePathInTempDir(t, "missing")})
+		So(err, ShouldNotBeNil)
+	})
+}
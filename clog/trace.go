@@ -0,0 +1,165 @@
+/*******************************************************************************
+ * Copyright (c) 2026 Genome Research Ltd.
+ *
+ * Author: Sendu Bala <sb10@sanger.ac.uk>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining
+ * a copy of this software and associated documentation files (the
+ * "Software"), to deal in the Software without restriction, including
+ * without limitation the rights to use, copy, modify, merge, publish,
+ * distribute, sublicense, and/or sell copies of the Software, and to
+ * permit persons to whom the Software is furnished to do so, subject to
+ * the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included
+ * in all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ ******************************************************************************/
+
+package clog
+
+// this file implements OpenTelemetry trace correlation and span creation.
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/inconshreveable/log15"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// TraceIDKey is the context key that the trace ID of the span in a context
+	// is logged under.
+	TraceIDKey = "trace_id"
+
+	// SpanIDKey is the context key that the span ID of the span in a context is
+	// logged under.
+	SpanIDKey = "span_id"
+
+	tracerName = "github.com/wtsi-ssg/wr"
+)
+
+// tracing holds the TracerProvider that StartSpan() uses.
+type tracing struct {
+	mu       sync.RWMutex
+	provider trace.TracerProvider
+}
+
+// tracer returns a Tracer from our provider, or from the global otel provider
+// if we don't have one.
+func (t *tracing) tracer() trace.Tracer {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.provider == nil {
+		return otel.GetTracerProvider().Tracer(tracerName)
+	}
+
+	return t.provider.Tracer(tracerName)
+}
+
+// setProvider sets our provider.
+func (t *tracing) setProvider(tp trace.TracerProvider) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.provider = tp
+}
+
+// tracer holds the TracerProvider set by SetTracerProvider().
+var tracer = &tracing{} //nolint:gochecknoglobals
+
+// SetTracerProvider sets the TracerProvider that StartSpan() uses. nil means
+// use the global otel TracerProvider, which is the default.
+func SetTracerProvider(tp trace.TracerProvider) {
+	tracer.setProvider(tp)
+}
+
+// SetSpanExporter makes StartSpan() use a new TracerProvider that exports
+// each span to the given exporter synchronously as soon as it ends, eg. a
+// tracetest.InMemoryExporter in tests. If you want batching, create your own
+// TracerProvider and use SetTracerProvider() instead.
+func SetSpanExporter(exporter sdktrace.SpanExporter) {
+	SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+}
+
+// EndSpanFunc is returned by StartSpan(), and should be called to end the
+// span, with the error (if any) of the thing the span was for.
+type EndSpanFunc func(err error)
+
+// StartSpan starts a new span with the given name as a child of any span in the
+// given context, returning a context containing the new span, and a function
+// to end it. keyvals are alternating keys and values that become attributes of
+// the span.
+//
+// Anything logged with the returned context will include the trace_id and
+// span_id of the new span.
+func StartSpan(ctx context.Context, name string, keyvals ...interface{}) (context.Context, EndSpanFunc) {
+	ctx, span := tracer.tracer().Start(ctx, name, trace.WithAttributes(keyvalsToAttributes(keyvals)...))
+
+	return ctx, func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+
+		span.End()
+	}
+}
+
+// keyvalsToAttributes converts alternating keys and values to span attributes.
+func keyvalsToAttributes(keyvals []interface{}) []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, 0, len(keyvals)/2) //nolint:mnd
+
+	for i := 0; i < len(keyvals)-1; i += 2 {
+		attrs = append(attrs, toAttribute(fieldKey(keyvals[i]), keyvals[i+1]))
+	}
+
+	return attrs
+}
+
+// toAttribute converts the given key and value to a span attribute, keeping
+// the type of common values. Durations are stored as strings like "1.5s".
+func toAttribute(key string, val interface{}) attribute.KeyValue {
+	switch v := val.(type) {
+	case string:
+		return attribute.String(key, v)
+	case int:
+		return attribute.Int(key, v)
+	case int64:
+		return attribute.Int64(key, v)
+	case float64:
+		return attribute.Float64(key, v)
+	case bool:
+		return attribute.Bool(key, v)
+	case time.Duration:
+		return attribute.String(key, v.String())
+	default:
+		return attribute.String(key, fmt.Sprint(v))
+	}
+}
+
+// addTraceToLogger checks if a valid span is in the context, and returns a new
+// logger with its trace and span IDs as context if so.
+func addTraceToLogger(ctx context.Context, logger log.Logger) log.Logger {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return logger
+	}
+
+	return logger.New(TraceIDKey, sc.TraceID().String(), SpanIDKey, sc.SpanID().String())
+}
@@ -0,0 +1,114 @@
+/*******************************************************************************
+ * Copyright (c) 2026 Genome Research Ltd.
+ *
+ * Author: Sendu Bala <sb10@sanger.ac.uk>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining
+ * a copy of this software and associated documentation files (the
+ * "Software"), to deal in the Software without restriction, including
+ * without limitation the rights to use, copy, modify, merge, publish,
+ * distribute, sublicense, and/or sell copies of the Software, and to
+ * permit persons to whom the Software is furnished to do so, subject to
+ * the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included
+ * in all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ ******************************************************************************/
+
+package clog
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var errSpan = errors.New("span err")
+
+func TestTrace(t *testing.T) {
+	background := context.Background()
+
+	Convey("Without a span in the context, no trace ids are logged", t, func() {
+		buff := ToBufferAtLevel("debug")
+		defer ToDefault()
+
+		Debug(background, "msg")
+		So(buff.String(), ShouldNotContainSubstring, TraceIDKey)
+
+		ctx, endSpan := StartSpan(background, "noop")
+		Debug(ctx, "msg")
+		endSpan(nil)
+		So(buff.String(), ShouldNotContainSubstring, TraceIDKey)
+	})
+
+	Convey("With a span exporter set", t, func() {
+		exporter := tracetest.NewInMemoryExporter()
+		SetSpanExporter(exporter)
+		defer SetTracerProvider(nil)
+
+		buff := ToBufferAtLevel("debug")
+		defer ToDefault()
+
+		ctx, endSpan := StartSpan(ContextWithJobKey(background, "bar"), "parent",
+			"str", "s", "int", 1, "int64", int64(2), "float", 1.5, "bool", true,
+			"dur", time.Second, "other", []int{1})
+		sc := trace.SpanContextFromContext(ctx)
+		So(sc.IsValid(), ShouldBeTrue)
+
+		Convey("records logged with the span's context include its trace and span ids", func() {
+			Debug(ctx, "msg")
+			So(buff.String(), ShouldContainSubstring, "jobkey=bar trace_id="+sc.TraceID().String()+
+				" span_id="+sc.SpanID().String())
+		})
+
+		Convey("spans are exported when ended, with their attributes", func() {
+			So(exporter.GetSpans(), ShouldBeEmpty)
+			endSpan(nil)
+
+			spans := exporter.GetSpans()
+			So(len(spans), ShouldEqual, 1)
+			So(spans[0].Name, ShouldEqual, "parent")
+			So(spans[0].SpanContext.TraceID(), ShouldEqual, sc.TraceID())
+			So(spans[0].Status.Code, ShouldEqual, codes.Unset)
+			So(spans[0].Attributes, ShouldResemble, []attribute.KeyValue{
+				attribute.String("str", "s"),
+				attribute.Int("int", 1),
+				attribute.Int64("int64", 2),
+				attribute.Float64("float", 1.5),
+				attribute.Bool("bool", true),
+				attribute.String("dur", "1s"),
+				attribute.String("other", "[1]"),
+			})
+		})
+
+		Convey("child spans share the trace id, and record errors", func() {
+			_, endChild := StartSpan(ctx, "child")
+			endChild(errSpan)
+			endSpan(nil)
+
+			spans := exporter.GetSpans()
+			So(len(spans), ShouldEqual, 2)
+			So(spans[0].Name, ShouldEqual, "child")
+			So(spans[0].Parent.SpanID(), ShouldEqual, sc.SpanID())
+			So(spans[0].SpanContext.TraceID(), ShouldEqual, sc.TraceID())
+			So(spans[0].Status.Code, ShouldEqual, codes.Error)
+			So(spans[0].Status.Description, ShouldEqual, errSpan.Error())
+			So(len(spans[0].Events), ShouldEqual, 1)
+		})
+	})
+}
@@ -26,9 +26,11 @@ package container
 
 import (
 	"context"
+	"errors"
 	"os"
 	"path/filepath"
 
+	"github.com/docker/docker/errdefs"
 	"github.com/wtsi-ssg/wr/fs/file"
 	fp "github.com/wtsi-ssg/wr/fs/filepath"
 )
@@ -44,6 +46,12 @@ const (
 	ErrContainerKill  OperationErr = "Container could not be killed"
 )
 
+// classifier is an error that knows if it is permanent, as per
+// retry.Classifier.
+type classifier interface {
+	Permanent() bool
+}
+
 // OperatorError records an error and a reason that caused it.
 type OperatorError struct {
 	Type OperationErr // one of our OperationErr constants
@@ -65,6 +73,19 @@ func (et *OperatorError) Unwrap() error {
 	return et.Err
 }
 
+// Permanent implements retry.Classifier. If the contained error has its own
+// classification, that is used. Otherwise errors saying that eg. the container
+// or image wasn't found, or that we aren't allowed, are permanent.
+func (et *OperatorError) Permanent() bool {
+	var c classifier
+	if errors.As(et.Err, &c) {
+		return c.Permanent()
+	}
+
+	return errdefs.IsNotFound(et.Err) || errdefs.IsInvalidParameter(et.Err) ||
+		errdefs.IsUnauthorized(et.Err) || errdefs.IsForbidden(et.Err)
+}
+
 // Interactor defines some methods to query containers.
 type Interactor interface {
 	ContainerList(ctx context.Context) ([]*Container, error)
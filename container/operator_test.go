@@ -32,12 +32,16 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/docker/docker/errdefs"
 	. "github.com/smartystreets/goconvey/convey"
+	"github.com/wtsi-ssg/wr/retry"
 )
 
 // fileMode is the mode of the temp file created for testing.
 const fileMode os.FileMode = 0600
 
+var errTest = errors.New("test error")
+
 // MockInteractor represents a mock implementation of container.Interactor.
 type MockInteractor struct {
 	ContainerListFn       func() ([]*Container, error)
@@ -407,3 +411,30 @@ func TestOperator(t *testing.T) {
 		})
 	})
 }
+
+func TestOperatorErrorPermanent(t *testing.T) {
+	Convey("OperatorErrors are permanent if the container or image can't be found or used", t, func() {
+		for _, err := range []error{
+			errdefs.NotFound(errTest),
+			errdefs.InvalidParameter(errTest),
+			errdefs.Unauthorized(errTest),
+			errdefs.Forbidden(errTest),
+		} {
+			So((&OperatorError{Type: ErrContainerKill, Err: err}).Permanent(), ShouldBeTrue)
+		}
+
+		So((&OperatorError{Type: ErrContainerList}).Permanent(), ShouldBeFalse)
+		So((&OperatorError{Type: ErrContainerList, Err: errTest}).Permanent(), ShouldBeFalse)
+		So((&OperatorError{Type: ErrContainerList, Err: errdefs.Unavailable(errTest)}).Permanent(), ShouldBeFalse)
+
+		Convey("unless the error they contain is classified", func() {
+			err := &OperatorError{Type: ErrContainerKill, Err: retry.Transient(errdefs.NotFound(errTest))}
+			So(err.Permanent(), ShouldBeFalse)
+			So(retry.IsPermanent(err), ShouldBeFalse)
+
+			err = &OperatorError{Type: ErrContainerKill, Err: retry.Permanent(errTest)}
+			So(err.Permanent(), ShouldBeTrue)
+			So(retry.IsPermanent(err), ShouldBeTrue)
+		})
+	})
+}
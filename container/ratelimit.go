@@ -0,0 +1,76 @@
+/*******************************************************************************
+ * Copyright (c) 2026 Genome Research Ltd.
+ *
+ * Author: Sendu Bala <sb10@sanger.ac.uk>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining
+ * a copy of this software and associated documentation files (the
+ * "Software"), to deal in the Software without restriction, including
+ * without limitation the rights to use, copy, modify, merge, publish,
+ * distribute, sublicense, and/or sell copies of the Software, and to
+ * permit persons to whom the Software is furnished to do so, subject to
+ * the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included
+ * in all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ ******************************************************************************/
+
+package container
+
+// this file has a decorator that rate limits an Interactor.
+
+import (
+	"context"
+
+	"github.com/wtsi-ssg/wr/ratelimit"
+)
+
+// rateLimitedInteractor is an Interactor that waits on a Limiter before each
+// call to another Interactor.
+type rateLimitedInteractor struct {
+	client  Interactor
+	limiter *ratelimit.Limiter
+}
+
+// NewRateLimitedInteractor returns an Interactor that calls the methods of the
+// given one, but only after waiting on the given Limiter, so that eg. a docker
+// daemon isn't overwhelmed by many Operators. If the wait fails because the
+// context ended, the context's error is returned without calling the method.
+func NewRateLimitedInteractor(client Interactor, limiter *ratelimit.Limiter) Interactor {
+	return &rateLimitedInteractor{client: client, limiter: limiter}
+}
+
+// ContainerList implements Interactor.
+func (r *rateLimitedInteractor) ContainerList(ctx context.Context) ([]*Container, error) {
+	if err := r.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	return r.client.ContainerList(ctx)
+}
+
+// ContainerStats implements Interactor.
+func (r *rateLimitedInteractor) ContainerStats(ctx context.Context, containerID string) (*Stats, error) {
+	if err := r.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	return r.client.ContainerStats(ctx, containerID)
+}
+
+// ContainerKill implements Interactor.
+func (r *rateLimitedInteractor) ContainerKill(ctx context.Context, containerID string) error {
+	if err := r.limiter.Wait(ctx); err != nil {
+		return err
+	}
+
+	return r.client.ContainerKill(ctx, containerID)
+}
@@ -0,0 +1,80 @@
+/*******************************************************************************
+ * Copyright (c) 2026 Genome Research Ltd.
+ *
+ * Author: Sendu Bala <sb10@sanger.ac.uk>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining
+ * a copy of this software and associated documentation files (the
+ * "Software"), to deal in the Software without restriction, including
+ * without limitation the rights to use, copy, modify, merge, publish,
+ * distribute, sublicense, and/or sell copies of the Software, and to
+ * permit persons to whom the Software is furnished to do so, subject to
+ * the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included
+ * in all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ ******************************************************************************/
+
+package container
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	bm "github.com/wtsi-ssg/wr/backoff/mock"
+	cm "github.com/wtsi-ssg/wr/clock/mock"
+	"github.com/wtsi-ssg/wr/ratelimit"
+)
+
+func TestRateLimitedInteractor(t *testing.T) {
+	Convey("A rate limited Interactor waits on its Limiter before each call", t, func() {
+		ctx := context.Background()
+		mock := &MockInteractor{
+			ContainerListFn:  func() ([]*Container, error) { return []*Container{{ID: "a"}}, nil },
+			ContainerStatsFn: func(string) (*Stats, error) { return &Stats{MemoryMB: 1}, nil },
+			ContainerKillFn:  func(string) error { return nil },
+		}
+		mc := cm.New(time.Now())
+		sleeper := &bm.Sleeper{Clock: mc}
+		client := NewRateLimitedInteractor(mock, &ratelimit.Limiter{Rate: 1, Burst: 1, Sleeper: sleeper, Clock: mc})
+
+		cntrs, err := client.ContainerList(ctx)
+		So(err, ShouldBeNil)
+		So(cntrs[0].ID, ShouldEqual, "a")
+		So(sleeper.Invoked(), ShouldEqual, 0)
+
+		stats, err := client.ContainerStats(ctx, "a")
+		So(err, ShouldBeNil)
+		So(stats.MemoryMB, ShouldEqual, 1)
+		So(sleeper.Invoked(), ShouldEqual, 1)
+
+		So(client.ContainerKill(ctx, "a"), ShouldBeNil)
+		So(sleeper.Invoked(), ShouldEqual, 2)
+		So(sleeper.Elapsed(), ShouldEqual, 2*time.Second)
+		So(mock.ContainerKillInvoked, ShouldEqual, 1)
+
+		Convey("but doesn't make the call if the context ends while waiting", func() {
+			cctx, cancel := context.WithCancel(ctx)
+			cancel()
+
+			_, err = client.ContainerList(cctx)
+			So(err, ShouldEqual, context.Canceled)
+			So(mock.ContainerListInvoked, ShouldEqual, 1)
+
+			_, err = client.ContainerStats(cctx, "a")
+			So(err, ShouldEqual, context.Canceled)
+			So(client.ContainerKill(cctx, "a"), ShouldEqual, context.Canceled)
+			So(mock.ContainerKillInvoked, ShouldEqual, 1)
+		})
+	})
+}
@@ -39,6 +39,9 @@ import (
 	"github.com/wtsi-ssg/wr/clog"
 )
 
+// logSubsystem is the name of the clog.Named() Logger we log with.
+const logSubsystem = "container"
+
 // dockerMountParts is the number of parts we expect to see after splitting
 // mount args on a colon.
 const dockerMountParts = 2
@@ -71,7 +74,7 @@ func createTmpFileAndCleanupMethod(ctx context.Context) (*os.File, func(), error
 	return f, func() {
 		errr := os.Remove(f.Name())
 		if errr != nil {
-			clog.Warn(ctx, "container command file could not be deleted", "err", errr)
+			clog.Named(logSubsystem).Warn(ctx, "container command file could not be deleted", "err", errr)
 		}
 	}, nil
 }
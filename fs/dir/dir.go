@@ -33,7 +33,8 @@ import (
 	"github.com/wtsi-ssg/wr/clog"
 )
 
-// GetPWD returns the present working directory and exits on error.
+// GetPWD returns the present working directory and exits on error, using
+// clog.Fatal() so that any clog.OnFatal() hooks get run first.
 func GetPWD(ctx context.Context) string {
 	pwd, err := os.Getwd()
 	if err != nil {
@@ -43,7 +44,8 @@ func GetPWD(ctx context.Context) string {
 	return pwd
 }
 
-// GetHome returns the home directory of current user and exits on error.
+// GetHome returns the home directory of current user and exits on error, using
+// clog.Fatal() so that any clog.OnFatal() hooks get run first.
 func GetHome(ctx context.Context) string {
 	home, herr := os.UserHomeDir()
 
@@ -27,7 +27,9 @@ package file
 // this file implements utility routines related to files.
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"strings"
 
@@ -45,6 +47,18 @@ func (p *PathReadError) Error() string {
 	return fmt.Sprintf("path [%s] could not be read: %s", p.path, p.Err)
 }
 
+// Unwrap returns the underlying error.
+func (p *PathReadError) Unwrap() error {
+	return p.Err
+}
+
+// Permanent implements retry.Classifier, returning true if there was no path,
+// the path doesn't exist, or permission was denied, since trying to read it
+// again won't help.
+func (p *PathReadError) Permanent() bool {
+	return p.Err == nil || errors.Is(p.Err, fs.ErrNotExist) || errors.Is(p.Err, fs.ErrPermission)
+}
+
 // GetFirstLine reads the content of a file given its absolute or tilda path and
 // returns the first line excluding trailing newline.
 func GetFirstLine(filename string) (string, error) {
@@ -25,6 +25,9 @@
 package file
 
 import (
+	"errors"
+	"io"
+	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
@@ -96,4 +99,24 @@ func TestFile(t *testing.T) {
 		So(content, ShouldEqual, "")
 		So(err, ShouldNotBeNil)
 	})
+
+	Convey("PathReadErrors wrap the underlying error and classify it", t, func() {
+		_, err := ToString("")
+
+		var pre *PathReadError
+		So(errors.As(err, &pre), ShouldBeTrue)
+		So(pre.Permanent(), ShouldBeTrue)
+
+		_, err = ToString("random.txt")
+		So(errors.Is(err, fs.ErrNotExist), ShouldBeTrue)
+		So(errors.As(err, &pre), ShouldBeTrue)
+		So(pre.Permanent(), ShouldBeTrue)
+
+		pre = &PathReadError{"path", fs.ErrPermission}
+		So(pre.Permanent(), ShouldBeTrue)
+
+		pre = &PathReadError{"path", io.ErrUnexpectedEOF}
+		So(pre.Permanent(), ShouldBeFalse)
+		So(pre.Unwrap(), ShouldEqual, io.ErrUnexpectedEOF)
+	})
 }
@@ -29,6 +29,7 @@ package fs
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/wtsi-ssg/wr/backoff"
 	"github.com/wtsi-ssg/wr/retry"
@@ -56,6 +57,12 @@ type Volume struct {
 
 	// UsageCalculator is an implementation of VolumeUsageCalculator.
 	UsageCalculator VolumeUsageCalculator
+
+	// AttemptTimeout, if greater than 0, is how long to wait for each attempt
+	// at getting the free space or size, before abandoning it (eg. because of
+	// a stalled filesystem) and trying again as if the answer were 0. An
+	// abandoned attempt must return before another is made.
+	AttemptTimeout time.Duration
 }
 
 // Size returns the size of the volume in GB.
@@ -106,6 +113,12 @@ type CheckedVolumeUsageCalculator struct {
 
 	// UsageCalculator is an implementation of VolumeUsageCalculator.
 	UsageCalculator VolumeUsageCalculator
+
+	// AttemptTimeout, if greater than 0, is how long to wait for each attempt
+	// at getting the free space or size, before abandoning it (eg. because of
+	// a stalled filesystem) and trying again as if the answer were 0. An
+	// abandoned attempt must return before another is made.
+	AttemptTimeout time.Duration
 }
 
 type volumeUsageCalculationMethod func(context.Context, string) uint64
@@ -113,54 +126,48 @@ type volumeUsageCalculationMethod func(context.Context, string) uint64
 // Size returns the size of the volume in bytes. If the answer would be
 // 0, this is first re-confirmed multiple times before returning.
 func (v *CheckedVolumeUsageCalculator) Size(ctx context.Context, volumePath string) uint64 {
-	return retryIfZero(ctx, v.Retries, v.Backoff, v.UsageCalculator.Size, volumePath)
+	return v.retryIfZero(ctx, v.UsageCalculator.Size, volumePath)
 }
 
 // Free returns the free space of the volume in bytes. If the answer would be
 // 0, this is first re-confirmed multiple times before returning.
 func (v *CheckedVolumeUsageCalculator) Free(ctx context.Context, volumePath string) uint64 {
-	return retryIfZero(ctx, v.Retries, v.Backoff, v.UsageCalculator.Free, volumePath)
+	return v.retryIfZero(ctx, v.UsageCalculator.Free, volumePath)
 }
 
-// retryIfZero retries the given method up to retries times if the method
-// returns zero. If it returns greater than zero, backoff is Reset().
-func retryIfZero(ctx context.Context,
-	retries int,
-	backoff *backoff.Backoff,
+// retryIfZero retries the given method up to Retries times if the method
+// returns zero or takes longer than AttemptTimeout. If it returns greater than
+// zero, Backoff is Reset().
+func (v *CheckedVolumeUsageCalculator) retryIfZero(ctx context.Context,
 	f volumeUsageCalculationMethod,
 	arg string) uint64 {
-	var bytes uint64
-	status := retry.Do(
+	bytes, status := retry.DoValue(
 		ctx,
-		operationReturnsErrIfZero(ctx, f, arg, &bytes),
+		retry.WithAttemptTimeout(operationReturnsErrIfZero(f, arg), v.AttemptTimeout),
 		&retry.Untils{
 			&retry.UntilNoError{},
-			&retry.UntilLimit{Max: retries},
+			&retry.UntilLimit{Max: v.Retries},
 		},
-		backoff,
+		v.Backoff,
 		"getting volume usage",
 	)
 
 	if status.StoppedBecause == retry.BecauseErrorNil {
-		backoff.Reset()
+		v.Backoff.Reset()
 	}
 
 	return bytes
 }
 
-// operationReturnsErrIfZero creates a retry.Operation that errors if supplied
-// method returns zero given supplied arg. The return value is stored in the
-// given bytes arg.
-func operationReturnsErrIfZero(ctx context.Context,
-	f volumeUsageCalculationMethod,
-	arg string,
-	bytes *uint64) retry.Operation {
-	return func() error {
-		*bytes = f(ctx, arg)
-		if *bytes == 0 {
-			return errZeroBytes
+// operationReturnsErrIfZero creates a retry.ValueOperation that returns what
+// the supplied method returns given supplied arg, erroring if that is zero.
+func operationReturnsErrIfZero(f volumeUsageCalculationMethod, arg string) retry.ValueOperation[uint64] {
+	return func(ctx context.Context) (uint64, error) {
+		bytes := f(ctx, arg)
+		if bytes == 0 {
+			return 0, errZeroBytes
 		}
 
-		return nil
+		return bytes, nil
 	}
 }
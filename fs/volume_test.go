@@ -143,6 +143,44 @@ func TestVolume(t *testing.T) {
 		})
 	})
 
+	Convey("When using a CheckedVolumeUsageCalculator with an AttemptTimeout, hung checks are abandoned", t, func() {
+		volume, m, _ := makeCheckedMockVolumeAndCalculator(5, 0*time.Millisecond, 0*time.Millisecond)
+		checked, ok := volume.UsageCalculator.(*CheckedVolumeUsageCalculator)
+		So(ok, ShouldBeTrue)
+		checked.AttemptTimeout = 50 * time.Millisecond
+
+		hang := make(chan struct{})
+		entered := make(chan struct{}, 5)
+		m.FreeFn = func(volumePath string) uint64 {
+			entered <- struct{}{}
+			if m.FreeInvoked == 1 {
+				<-hang
+			}
+
+			return gb
+		}
+
+		Convey("and retried once they return", func() {
+			go func() {
+				<-entered
+				<-time.After(75 * time.Millisecond)
+				close(hang)
+			}()
+
+			So(volume.NoSpaceLeft(ctx), ShouldBeFalse)
+			So(m.FreeInvoked, ShouldEqual, 2)
+		})
+
+		Convey("instead of blocking forever", func() {
+			defer close(hang)
+
+			start := time.Now()
+			So(volume.NoSpaceLeft(ctx), ShouldBeTrue)
+			So(time.Since(start), ShouldBeLessThan, time.Second)
+			So(len(entered), ShouldEqual, 1)
+		})
+	})
+
 	Convey("When using a CheckedVolumeUsageCalculator, size is checked multiple times if 0", t, func() {
 		attempts := 3
 		volume, m, bm := makeCheckedMockVolumeAndCalculator(attempts, 2*time.Millisecond, 1*time.Hour)
@@ -0,0 +1,229 @@
+/*******************************************************************************
+ * Copyright (c) 2026 Genome Research Ltd.
+ *
+ * Author: Sendu Bala <sb10@sanger.ac.uk>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining
+ * a copy of this software and associated documentation files (the
+ * "Software"), to deal in the Software without restriction, including
+ * without limitation the rights to use, copy, modify, merge, publish,
+ * distribute, sublicense, and/or sell copies of the Software, and to
+ * permit persons to whom the Software is furnished to do so, subject to
+ * the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included
+ * in all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ ******************************************************************************/
+
+// package ratelimit is used to limit how often something is done, eg. calls to
+// a cloud or docker API.
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/wtsi-ssg/wr/backoff"
+	bt "github.com/wtsi-ssg/wr/backoff/time"
+	"github.com/wtsi-ssg/wr/clock"
+)
+
+// Limiter is a token bucket that lets something be done at a steady Rate,
+// with bursts of up to Burst at once. The bucket starts full. It is safe for
+// concurrent use, but you must not change its fields after first use.
+type Limiter struct {
+	// Rate is the number of tokens added to the bucket per second. 0 means
+	// there is no limit.
+	Rate float64
+
+	// Burst is the size of the bucket, the most tokens that can be acquired
+	// at once without waiting. Values less than 1 are treated as 1.
+	Burst int
+
+	// Sleeper is used by Wait() to wait for a token. Defaults to a
+	// backoff/time.Sleeper using Clock.
+	Sleeper backoff.Sleeper
+
+	// Clock is used to tell how long it has been since tokens were last
+	// acquired. Defaults to real time. To share a mock clock with a default
+	// Sleeper, set it here.
+	Clock clock.Clock
+
+	mu      sync.Mutex
+	started bool
+	tokens  float64
+	last    time.Time
+}
+
+// Wait acquires a token, waiting (using Sleeper.Sleep()) until one is
+// available if necessary.
+//
+// If the context has already ended, ends while waiting, or has a deadline
+// (according to Clock) that would pass before a token became available, no
+// token is acquired and the context's error is returned without waiting any
+// further.
+func (l *Limiter) Wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	d := l.reserve()
+	if d <= 0 {
+		return nil
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && deadline.Sub(l.now()) < d {
+		l.unreserve()
+
+		return context.DeadlineExceeded
+	}
+
+	if err := l.sleeper().Sleep(ctx, d); err != nil {
+		l.unreserve()
+
+		return err
+	}
+
+	return nil
+}
+
+// reserve takes a token from the bucket, returning how long must be waited
+// before it is actually available.
+func (l *Limiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.Rate <= 0 {
+		return 0
+	}
+
+	l.refill()
+	l.tokens--
+
+	if l.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-l.tokens / l.Rate * float64(time.Second))
+}
+
+// unreserve puts back a token taken by reserve().
+func (l *Limiter) unreserve() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.refill()
+	l.tokens = min(l.tokens+1, l.burst())
+}
+
+// refill adds the tokens accrued since the last refill to the bucket, starting
+// with a full bucket. You must hold the lock.
+func (l *Limiter) refill() {
+	now := l.now()
+
+	if !l.started {
+		l.started = true
+		l.tokens = l.burst()
+		l.last = now
+
+		return
+	}
+
+	if elapsed := now.Sub(l.last); elapsed > 0 {
+		l.tokens = min(l.tokens+elapsed.Seconds()*l.Rate, l.burst())
+		l.last = now
+	}
+}
+
+// burst returns Burst, but at least 1.
+func (l *Limiter) burst() float64 {
+	return float64(max(l.Burst, 1))
+}
+
+// now returns the current time according to our Clock.
+func (l *Limiter) now() time.Time {
+	if l.Clock == nil {
+		return time.Now()
+	}
+
+	return l.Clock.Now()
+}
+
+// sleeper returns our Sleeper, or a real one using our Clock.
+func (l *Limiter) sleeper() backoff.Sleeper {
+	if l.Sleeper == nil {
+		return &bt.Sleeper{Clock: l.Clock}
+	}
+
+	return l.Sleeper
+}
+
+// TryAcquire acquires a token if one is available right now, returning true
+// if so. It never waits.
+func (l *Limiter) TryAcquire() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.Rate <= 0 {
+		return true
+	}
+
+	l.refill()
+
+	if l.tokens < 1 {
+		return false
+	}
+
+	l.tokens--
+
+	return true
+}
+
+// Keyed hands out a separate Limiter per key, eg. per API endpoint or cloud
+// region. It is safe for concurrent use.
+type Keyed struct {
+	// New is called to make the Limiter for a key the first time it is
+	// needed.
+	New func(key string) *Limiter
+
+	mu       sync.Mutex
+	limiters map[string]*Limiter
+}
+
+// Get returns the Limiter for the given key, making it with New() if
+// necessary.
+func (k *Keyed) Get(key string) *Limiter {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.limiters == nil {
+		k.limiters = make(map[string]*Limiter)
+	}
+
+	l, found := k.limiters[key]
+	if !found {
+		l = k.New(key)
+		k.limiters[key] = l
+	}
+
+	return l
+}
+
+// Wait calls Wait() on the Limiter for the given key.
+func (k *Keyed) Wait(ctx context.Context, key string) error {
+	return k.Get(key).Wait(ctx)
+}
+
+// TryAcquire calls TryAcquire() on the Limiter for the given key.
+func (k *Keyed) TryAcquire(key string) bool {
+	return k.Get(key).TryAcquire()
+}
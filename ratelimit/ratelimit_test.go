@@ -0,0 +1,188 @@
+/*******************************************************************************
+ * Copyright (c) 2026 Genome Research Ltd.
+ *
+ * Author: Sendu Bala <sb10@sanger.ac.uk>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining
+ * a copy of this software and associated documentation files (the
+ * "Software"), to deal in the Software without restriction, including
+ * without limitation the rights to use, copy, modify, merge, publish,
+ * distribute, sublicense, and/or sell copies of the Software, and to
+ * permit persons to whom the Software is furnished to do so, subject to
+ * the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included
+ * in all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ ******************************************************************************/
+
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	bm "github.com/wtsi-ssg/wr/backoff/mock"
+	"github.com/wtsi-ssg/wr/clock"
+	cm "github.com/wtsi-ssg/wr/clock/mock"
+)
+
+func TestLimiter(t *testing.T) {
+	background := context.Background()
+
+	Convey("Given a Limiter with a mock clock", t, func() {
+		mc := cm.New(time.Now())
+		sleeper := &bm.Sleeper{Clock: mc}
+		l := &Limiter{Rate: 10, Burst: 2, Sleeper: sleeper, Clock: mc}
+
+		Convey("Wait() doesn't wait for the first Burst tokens", func() {
+			So(l.Wait(background), ShouldBeNil)
+			So(l.Wait(background), ShouldBeNil)
+			So(sleeper.Invoked(), ShouldEqual, 0)
+
+			Convey("then waits for tokens to be added at Rate", func() {
+				So(l.Wait(background), ShouldBeNil)
+				So(sleeper.Invoked(), ShouldEqual, 1)
+				So(sleeper.Elapsed(), ShouldEqual, 100*time.Millisecond)
+
+				So(l.Wait(background), ShouldBeNil)
+				So(sleeper.Elapsed(), ShouldEqual, 200*time.Millisecond)
+			})
+
+			Convey("and doesn't take a token if the context ends first", func() {
+				ctx, cancel := context.WithCancel(background)
+				cancel()
+				So(l.Wait(ctx), ShouldEqual, context.Canceled)
+
+				mc.Advance(100 * time.Millisecond)
+				So(l.TryAcquire(), ShouldBeTrue)
+				So(l.TryAcquire(), ShouldBeFalse)
+			})
+
+			Convey("and doesn't wait at all if the context's deadline is too soon", func() {
+				ctx, cancel := context.WithTimeout(background, time.Millisecond)
+				defer cancel()
+
+				So(errors.Is(l.Wait(ctx), context.DeadlineExceeded), ShouldBeTrue)
+				So(sleeper.Invoked(), ShouldEqual, 0)
+			})
+
+			Convey("judging how soon the deadline is by its Clock", func() {
+				mc.Advance(time.Hour)
+				So(l.TryAcquire(), ShouldBeTrue)
+				So(l.TryAcquire(), ShouldBeTrue)
+
+				ctx, cancel := context.WithDeadline(background, mc.Now().Add(50*time.Millisecond))
+				defer cancel()
+
+				So(errors.Is(l.Wait(ctx), context.DeadlineExceeded), ShouldBeTrue)
+				So(sleeper.Invoked(), ShouldEqual, 0)
+			})
+		})
+
+		Convey("TryAcquire() never waits", func() {
+			So(l.TryAcquire(), ShouldBeTrue)
+			So(l.TryAcquire(), ShouldBeTrue)
+			So(l.TryAcquire(), ShouldBeFalse)
+
+			mc.Advance(50 * time.Millisecond)
+			So(l.TryAcquire(), ShouldBeFalse)
+
+			mc.Advance(50 * time.Millisecond)
+			So(l.TryAcquire(), ShouldBeTrue)
+
+			Convey("and the bucket refills up to Burst", func() {
+				mc.Advance(time.Hour)
+				So(l.TryAcquire(), ShouldBeTrue)
+				So(l.TryAcquire(), ShouldBeTrue)
+				So(l.TryAcquire(), ShouldBeFalse)
+				So(sleeper.Invoked(), ShouldEqual, 0)
+			})
+		})
+
+		Convey("It can be used concurrently", func() {
+			var wg sync.WaitGroup
+
+			for range 10 {
+				wg.Add(1)
+
+				go func() {
+					defer wg.Done()
+
+					l.Wait(background) //nolint:errcheck
+				}()
+			}
+
+			wg.Wait()
+			So(sleeper.Invoked(), ShouldEqual, 8)
+			So(l.TryAcquire(), ShouldBeFalse)
+		})
+	})
+
+	Convey("A Limiter with no Rate never limits", t, func() {
+		l := &Limiter{}
+
+		for range 100 {
+			So(l.TryAcquire(), ShouldBeTrue)
+		}
+
+		So(l.Wait(background), ShouldBeNil)
+	})
+
+	Convey("A Limiter's default Sleeper waits on its Clock", t, func() {
+		mc := cm.New(time.Now())
+		l := &Limiter{Rate: 1, Burst: 1, Clock: mc}
+		So(l.Wait(background), ShouldBeNil)
+
+		done := make(chan error)
+
+		go func() { done <- l.Wait(clock.ContextWithClock(background, cm.New(time.Now()))) }()
+
+		mc.BlockUntil(1)
+		mc.Advance(time.Second)
+		So(<-done, ShouldBeNil)
+
+		Convey("giving back the token if the context is cancelled while waiting", func() {
+			ctx, cancel := context.WithCancel(background)
+
+			go func() { done <- l.Wait(ctx) }()
+
+			mc.BlockUntil(1)
+			cancel()
+			So(<-done, ShouldEqual, context.Canceled)
+
+			mc.Advance(time.Second)
+			So(l.TryAcquire(), ShouldBeTrue)
+		})
+	})
+}
+
+func TestKeyed(t *testing.T) {
+	background := context.Background()
+
+	Convey("Keyed limits each key separately", t, func() {
+		sleeper := &bm.Sleeper{}
+		k := &Keyed{New: func(string) *Limiter { return &Limiter{Rate: 1, Burst: 1, Sleeper: sleeper} }}
+
+		So(k.Get("a"), ShouldEqual, k.Get("a"))
+		So(k.TryAcquire("a"), ShouldBeTrue)
+		So(k.TryAcquire("a"), ShouldBeFalse)
+		So(k.TryAcquire("b"), ShouldBeTrue)
+
+		So(k.Wait(background, "c"), ShouldBeNil)
+		So(sleeper.Invoked(), ShouldEqual, 0)
+		So(k.Wait(background, "c"), ShouldBeNil)
+		So(sleeper.Invoked(), ShouldEqual, 1)
+	})
+}
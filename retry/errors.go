@@ -0,0 +1,92 @@
+/*******************************************************************************
+ * Copyright (c) 2026 Genome Research Ltd.
+ *
+ * Author: Sendu Bala <sb10@sanger.ac.uk>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining
+ * a copy of this software and associated documentation files (the
+ * "Software"), to deal in the Software without restriction, including
+ * without limitation the rights to use, copy, modify, merge, publish,
+ * distribute, sublicense, and/or sell copies of the Software, and to
+ * permit persons to whom the Software is furnished to do so, subject to
+ * the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included
+ * in all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ ******************************************************************************/
+
+package retry
+
+// this file implements classification of errors as permanent or transient.
+
+import "errors"
+
+// Classifier is implemented by errors that know whether trying again to do
+// what caused them could succeed.
+type Classifier interface {
+	// Permanent returns true if trying again can't help.
+	Permanent() bool
+}
+
+// classifiedError wraps an error to classify it.
+type classifiedError struct {
+	err       error
+	permanent bool
+}
+
+// Error returns the wrapped error's message.
+func (c *classifiedError) Error() string {
+	return c.err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (c *classifiedError) Unwrap() error {
+	return c.err
+}
+
+// Permanent implements Classifier.
+func (c *classifiedError) Permanent() bool {
+	return c.permanent
+}
+
+// Permanent wraps the given error to classify it as permanent, so that
+// UntilPermanentError will stop retries straight away. Returns nil if err is
+// nil.
+func Permanent(err error) error {
+	return classify(err, true)
+}
+
+// Transient wraps the given error to classify it as transient, overriding any
+// permanent classification of the error it wraps. Returns nil if err is nil.
+func Transient(err error) error {
+	return classify(err, false)
+}
+
+// classify wraps err in a classifiedError, unless it is nil.
+func classify(err error, permanent bool) error {
+	if err == nil {
+		return nil
+	}
+
+	return &classifiedError{err: err, permanent: permanent}
+}
+
+// IsPermanent returns true if the outermost Classifier in err's chain (as
+// found by errors.As()) says it is Permanent(). Errors without a Classifier
+// are transient.
+func IsPermanent(err error) bool {
+	var c Classifier
+	if errors.As(err, &c) {
+		return c.Permanent()
+	}
+
+	return false
+}
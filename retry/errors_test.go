@@ -0,0 +1,59 @@
+/*******************************************************************************
+ * Copyright (c) 2026 Genome Research Ltd.
+ *
+ * Author: Sendu Bala <sb10@sanger.ac.uk>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining
+ * a copy of this software and associated documentation files (the
+ * "Software"), to deal in the Software without restriction, including
+ * without limitation the rights to use, copy, modify, merge, publish,
+ * distribute, sublicense, and/or sell copies of the Software, and to
+ * permit persons to whom the Software is furnished to do so, subject to
+ * the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included
+ * in all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ ******************************************************************************/
+
+package retry
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestErrors(t *testing.T) {
+	Convey("Errors are transient unless classified as permanent", t, func() {
+		So(IsPermanent(nil), ShouldBeFalse)
+		So(IsPermanent(ErrNormal), ShouldBeFalse)
+
+		perm := Permanent(ErrNormal)
+		So(IsPermanent(perm), ShouldBeTrue)
+		So(perm.Error(), ShouldEqual, ErrNormal.Error())
+		So(errors.Is(perm, ErrNormal), ShouldBeTrue)
+		So(IsPermanent(fmt.Errorf("wrapped: %w", perm)), ShouldBeTrue)
+
+		Convey("with the outermost classification winning", func() {
+			trans := Transient(perm)
+			So(IsPermanent(trans), ShouldBeFalse)
+			So(errors.Is(trans, ErrNormal), ShouldBeTrue)
+			So(IsPermanent(Permanent(trans)), ShouldBeTrue)
+		})
+
+		Convey("and nil errors stay nil", func() {
+			So(Permanent(nil), ShouldBeNil)
+			So(Transient(nil), ShouldBeNil)
+		})
+	})
+}
@@ -0,0 +1,161 @@
+/*******************************************************************************
+ * Copyright (c) 2026 Genome Research Ltd.
+ *
+ * Author: Sendu Bala <sb10@sanger.ac.uk>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining
+ * a copy of this software and associated documentation files (the
+ * "Software"), to deal in the Software without restriction, including
+ * without limitation the rights to use, copy, modify, merge, publish,
+ * distribute, sublicense, and/or sell copies of the Software, and to
+ * permit persons to whom the Software is furnished to do so, subject to
+ * the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included
+ * in all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ ******************************************************************************/
+
+package retry
+
+// this file implements recording and observing the history of retries.
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/wtsi-ssg/wr/backoff"
+	"github.com/wtsi-ssg/wr/clock"
+	"github.com/wtsi-ssg/wr/clog"
+)
+
+// Attempt records a run of an Operation.
+type Attempt struct {
+	// Start is when the run started.
+	Start time.Time
+
+	// Duration is how long the run took.
+	Duration time.Duration
+
+	// Err is what the run returned.
+	Err error
+
+	// Sleep is how long the backoff sleep after the run took; 0 if there was
+	// no sleep because retries stopped.
+	Sleep time.Duration
+}
+
+// Observer can be passed to Do() (and its variants) to be told what happens as
+// it goes along, eg. to report progress or collect metrics. Its methods are
+// called synchronously, so should be quick.
+type Observer interface {
+	// OnAttempt is called after each run of the Operation, with the number of
+	// the attempt, starting from 0.
+	OnAttempt(ctx context.Context, num int, attempt Attempt)
+
+	// OnSleep is called after each backoff sleep, with the number of the
+	// attempt it followed and how long the sleep took.
+	OnSleep(ctx context.Context, num int, slept time.Duration)
+
+	// OnStop is called once retries have stopped, with the final Status.
+	OnStop(ctx context.Context, status *Status)
+}
+
+// history builds up a Status as retries happen, telling Observers.
+type history struct {
+	clock     clock.Clock
+	deadline  time.Time
+	observers []Observer
+	status    *Status
+}
+
+// newHistory returns a history that times things using the given clock, and
+// cuts sleeps short at the given deadline, unless it is the zero time.
+func newHistory(c clock.Clock, deadline time.Time, observers []Observer) *history {
+	return &history{clock: c, deadline: deadline, observers: observers, status: &Status{}}
+}
+
+// attempted records an attempt that started at the given time and returned the
+// given error.
+func (h *history) attempted(ctx context.Context, start time.Time, err error) {
+	a := Attempt{Start: start, Duration: h.clock.Now().Sub(start), Err: err}
+	h.status.Attempts = append(h.status.Attempts, a)
+
+	for _, o := range h.observers {
+		o.OnAttempt(ctx, len(h.status.Attempts)-1, a)
+	}
+}
+
+// tryAgain tests reason to see if we should try again, and if so, uses the
+// backoff to sleep and increments Retried before returning. If the context
+// ends during the sleep, reason is set to BecauseContextClosed and we don't try
+// again; likewise if our deadline is reached, with BecauseTimeLimit.
+func (h *history) tryAgain(ctx context.Context, bo *backoff.Backoff, reason *Reason) bool {
+	if *reason != doNotStop {
+		return false
+	}
+
+	start := h.clock.Now()
+	err := h.sleep(clog.ContextWithRetryNum(ctx, h.status.Retried+1), bo)
+	h.slept(ctx, h.clock.Now().Sub(start))
+
+	if err != nil {
+		*reason = sleepStopReason(err)
+
+		return false
+	}
+
+	h.status.Retried++
+
+	return true
+}
+
+// sleep uses the backoff to sleep, stopping at our deadline (according to our
+// clock) if we have one.
+func (h *history) sleep(ctx context.Context, bo *backoff.Backoff) error {
+	if h.deadline.IsZero() {
+		return bo.Sleep(ctx)
+	}
+
+	return bo.SleepAtMost(ctx, h.deadline.Sub(h.clock.Now()))
+}
+
+// sleepStopReason returns the Reason to stop retrying given the error from an
+// interrupted sleep.
+func sleepStopReason(err error) Reason {
+	if errors.Is(err, backoff.ErrSleepLimit) {
+		return BecauseTimeLimit
+	}
+
+	return BecauseContextClosed
+}
+
+// slept records the sleep after the latest attempt.
+func (h *history) slept(ctx context.Context, d time.Duration) {
+	num := len(h.status.Attempts) - 1
+	h.status.Attempts[num].Sleep = d
+
+	for _, o := range h.observers {
+		o.OnSleep(ctx, num, d)
+	}
+}
+
+// stop finalises and returns our Status.
+func (h *history) stop(ctx context.Context, reason Reason, err error) *Status {
+	h.status.StoppedBecause = reason
+	h.status.Err = err
+
+	for _, o := range h.observers {
+		o.OnStop(ctx, h.status)
+	}
+
+	return h.status
+}
@@ -0,0 +1,128 @@
+/*******************************************************************************
+ * Copyright (c) 2026 Genome Research Ltd.
+ *
+ * Author: Sendu Bala <sb10@sanger.ac.uk>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining
+ * a copy of this software and associated documentation files (the
+ * "Software"), to deal in the Software without restriction, including
+ * without limitation the rights to use, copy, modify, merge, publish,
+ * distribute, sublicense, and/or sell copies of the Software, and to
+ * permit persons to whom the Software is furnished to do so, subject to
+ * the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included
+ * in all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ ******************************************************************************/
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/wtsi-ssg/wr/backoff"
+	bm "github.com/wtsi-ssg/wr/backoff/mock"
+	"github.com/wtsi-ssg/wr/clock"
+	cm "github.com/wtsi-ssg/wr/clock/mock"
+)
+
+var ErrOther = errors.New("other err")
+
+// recordingObserver is an Observer that records what it is told.
+type recordingObserver struct {
+	events []string
+	status *Status
+}
+
+// OnAttempt implements Observer.
+func (r *recordingObserver) OnAttempt(ctx context.Context, num int, attempt Attempt) {
+	r.events = append(r.events, fmt.Sprintf("attempt %d took %s: %v", num, attempt.Duration, attempt.Err))
+}
+
+// OnSleep implements Observer.
+func (r *recordingObserver) OnSleep(ctx context.Context, num int, slept time.Duration) {
+	r.events = append(r.events, fmt.Sprintf("slept %s after %d", slept, num))
+}
+
+// OnStop implements Observer.
+func (r *recordingObserver) OnStop(ctx context.Context, status *Status) {
+	r.events = append(r.events, "stopped")
+	r.status = status
+}
+
+func TestHistory(t *testing.T) {
+	Convey("Given a mock clock and an operation that takes a second", t, func() {
+		start := time.Now()
+		mc := cm.New(start)
+		ctx := clock.ContextWithClock(context.Background(), mc)
+		bo := &backoff.Backoff{Min: 2 * time.Second, Max: 2 * time.Second, Factor: 1, Sleeper: &bm.Sleeper{Clock: mc}}
+		errs := []error{ErrOp, ErrOther, ErrOp, nil}
+		count := 0
+
+		op := func() error {
+			mc.Advance(time.Second)
+			count++
+
+			return errs[count-1]
+		}
+
+		Convey("Do() records each attempt in the Status", func() {
+			status := Do(ctx, op, &UntilNoError{}, bo, "doing foo")
+			So(status.Retried, ShouldEqual, 3)
+			So(len(status.Attempts), ShouldEqual, 4)
+
+			for i, a := range status.Attempts {
+				So(a.Start, ShouldEqual, start.Add(time.Duration(i)*3*time.Second))
+				So(a.Duration, ShouldEqual, time.Second)
+				So(a.Err, ShouldEqual, errs[i])
+			}
+
+			So(status.Attempts[0].Sleep, ShouldEqual, 2*time.Second)
+			So(status.Attempts[3].Sleep, ShouldEqual, 0)
+			So(status.Elapsed(), ShouldEqual, 10*time.Second)
+
+			joined := status.Errors()
+			So(errors.Is(joined, ErrOp), ShouldBeTrue)
+			So(errors.Is(joined, ErrOther), ShouldBeTrue)
+			So(joined.Error(), ShouldEqual, "op err\nother err")
+		})
+
+		Convey("Do() tells Observers what happens", func() {
+			o1, o2 := &recordingObserver{}, &recordingObserver{}
+			errs = []error{ErrOp, nil}
+
+			status := Do(ctx, op, &UntilNoError{}, bo, "doing foo", o1, o2)
+			So(o1.events, ShouldResemble, []string{
+				"attempt 0 took 1s: op err",
+				"slept 2s after 0",
+				"attempt 1 took 1s: <nil>",
+				"stopped",
+			})
+			So(o2.events, ShouldResemble, o1.events)
+			So(o1.status, ShouldEqual, status)
+		})
+	})
+
+	Convey("A Status without Attempts has no elapsed time or errors", t, func() {
+		status := &Status{}
+		So(status.Elapsed(), ShouldEqual, 0)
+		So(status.Errors(), ShouldBeNil)
+
+		status.Attempts = []Attempt{{Duration: time.Second}}
+		So(status.Elapsed(), ShouldEqual, time.Second)
+		So(status.Errors(), ShouldBeNil)
+	})
+}
@@ -28,15 +28,29 @@ package retry
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/wtsi-ssg/wr/backoff"
+	"github.com/wtsi-ssg/wr/clock"
 	"github.com/wtsi-ssg/wr/clog"
 )
 
+// logSubsystem is the name of the clog.Named() Logger we log with.
+const logSubsystem = "retry"
+
 // Operation is passed to Do() and is the code you would like to retry.
 type Operation func() error
 
+// OperationCtx is passed to DoCtx() and is the code you would like to retry,
+// when it can be stopped early by ending the context it is passed.
+type OperationCtx func(context.Context) error
+
+// ValueOperation is passed to DoValue() and is the code you would like to
+// retry, when it returns a value. It is passed a context for the attempt.
+type ValueOperation[T any] func(context.Context) (T, error)
+
 // Status is returned by Do() to explain what happened when retrying your
 // Operation. It can be stringified or used as an error that wraps Err.
 type Status struct { //nolint:errname
@@ -49,6 +63,9 @@ type Status struct { //nolint:errname
 
 	// Err is the last return value of the Operation.
 	Err error
+
+	// Attempts records each run of the Operation, in order.
+	Attempts []Attempt
 }
 
 // String returns a string representation of the Status.
@@ -71,53 +88,223 @@ func (s *Status) Unwrap() error {
 	return s.Err
 }
 
+// Elapsed returns the total time taken, from the start of the first Attempt to
+// the end of the sleep (if any) after the last.
+func (s *Status) Elapsed() time.Duration {
+	if len(s.Attempts) == 0 {
+		return 0
+	}
+
+	last := s.Attempts[len(s.Attempts)-1]
+
+	return last.Start.Add(last.Duration + last.Sleep).Sub(s.Attempts[0].Start)
+}
+
+// Errors returns an errors.Join() of the distinct (by message) errors returned
+// by our Attempts, in the order they first occurred. Returns nil if none of
+// them returned an error.
+func (s *Status) Errors() error {
+	var errs []error
+
+	seen := make(map[string]bool)
+
+	for _, a := range s.Attempts {
+		if a.Err == nil || seen[a.Err.Error()] {
+			continue
+		}
+
+		seen[a.Err.Error()] = true
+		errs = append(errs, a.Err)
+	}
+
+	return errors.Join(errs...)
+}
+
 // Do will run op at least once, and then will keep retrying it unless the until
 // returns a Reason to stop, or the context has been cancelled. The amount of
 // time between retries is determined by bo.
 //
 // The context is also used to end bo's sleep early, if cancelled during a
-// sleep.
+// sleep, and to cut short a sleep that would go past its deadline. In both cases
+// we stop straight away with BecauseContextClosed, without trying op again. If
+// bo's Sleeper gets its clock.Clock from the context (as
+// backoff/time.Sleeper does by default), you can use clock.ContextWithClock()
+// to make the sleeps follow a different clock, eg. a mock one in tests.
+//
+// The returned Status records each attempt, timed using the clock.Clock of the
+// context. Any given Observers are told about attempts, sleeps and the final
+// Status as they happen.
 //
 // If any retries were required, the returned Status is logged using the global
-// context logger at debug level. Any Backoff sleeps will have been logged
+// context logger at debug level, with the subsystem "retry", along with the
+// total elapsed time. Any Backoff sleeps will have been logged
 // sharing a unique retryset id, and a retrynum. All logs will include the given
 // activity.
 //
+// The whole of Do is recorded as a "retry" span using clog.StartSpan(), with
+// each run of op recorded as a child "retry.attempt" span.
+//
 // Note that bo is NOT Reset() during this function.
-func Do(ctx context.Context, op Operation, until Until, bo *backoff.Backoff, activity string) *Status {
+func Do(ctx context.Context, op Operation, until Until, bo *backoff.Backoff, activity string,
+	observers ...Observer) *Status {
+	_, status := DoValue(ctx, func(context.Context) (struct{}, error) {
+		return struct{}{}, op()
+	}, until, bo, activity, observers...)
+
+	return status
+}
+
+// DoValue is like Do(), but for an op that returns a value as well as an error.
+// The value returned by the last run of op is returned along with the Status,
+// even if that run failed.
+//
+// op is passed a context that carries the "retry.attempt" span of its run.
+func DoValue[T any](ctx context.Context, op ValueOperation[T], until Until, bo *backoff.Backoff,
+	activity string, observers ...Observer) (T, *Status) {
 	var (
-		reason  Reason
-		retries int
-		err     error
+		value  T
+		reason Reason
+		err    error
 	)
 
-	until = Untils{until, &untilContext{Context: ctx}}
+	c := clock.FromContext(ctx)
+	untils := Untils{until, &untilContext{Context: ctx}}.bound(c)
 
 	ctx = clog.ContextForRetries(ctx, activity)
+	ctx, endSpan := clog.StartSpan(ctx, "retry", "retryactivity", activity)
+	h := newHistory(c, untils.deadline(), observers)
 
-	for ok := true; ok; ok = tryAgain(ctx, bo, reason, &retries) {
-		err = op()
-		reason = until.ShouldStop(retries, err)
+	for ok := true; ok; ok = h.tryAgain(ctx, bo, &reason) {
+		value, err = attempt(ctx, op, h)
+		reason = untils.ShouldStop(h.status.Retried, err)
 	}
 
-	status := &Status{Retried: retries, StoppedBecause: reason, Err: err}
+	status := h.stop(ctx, reason, err)
 	logStatusIfRetried(ctx, status)
+	endSpan(status.Err)
+
+	return value, status
+}
+
+// DoCtx is like Do(), but op is passed a context that ends after attemptTimeout
+// (if greater than 0) or when ctx ends, so that a hung attempt is abandoned and
+// can be retried. See WithAttemptTimeout().
+func DoCtx(ctx context.Context, op OperationCtx, attemptTimeout time.Duration, until Until,
+	bo *backoff.Backoff, activity string, observers ...Observer) *Status {
+	_, status := DoValue(ctx, WithAttemptTimeout(func(actx context.Context) (struct{}, error) {
+		return struct{}{}, op(actx)
+	}, attemptTimeout), until, bo, activity, observers...)
 
 	return status
 }
 
-// tryAgain tests reason to see if we should try again, and if so, increments
-// retries and uses the backoff to sleep before returning.
-func tryAgain(ctx context.Context, bo *backoff.Backoff, reason Reason, retries *int) bool {
-	if reason != doNotStop {
-		return false
+// WithAttemptTimeout wraps op so that each run of it is passed a context that
+// ends after the given timeout. If op hasn't returned by then, the run is
+// abandoned, returning the zero value and the context's error. op itself is
+// left to return in the background (eg. from a stalled statfs call), and what
+// it returns is discarded.
+//
+// op is never run again while an abandoned run of it is still going, so there
+// is at most one abandoned run at a time. Until it returns, later runs wait for
+// it (for up to their own timeout), returning the context's error if it still
+// hasn't returned.
+//
+// A timeout of 0 or less returns op unaltered.
+func WithAttemptTimeout[T any](op ValueOperation[T], timeout time.Duration) ValueOperation[T] {
+	if timeout <= 0 {
+		return op
 	}
 
-	*retries++
+	slot := make(chan struct{}, 1)
+
+	return func(ctx context.Context) (T, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		return runInSlot(ctx, op, slot)
+	}
+}
+
+// result holds what a ValueOperation returned.
+type result[T any] struct {
+	value T
+	err   error
+}
+
+// runInSlot waits for the given slot to be free, then runs op in the
+// background, keeping the slot until op returns. It returns what op returns,
+// or the zero value and the context's error if the context ends first.
+func runInSlot[T any](ctx context.Context, op ValueOperation[T], slot chan struct{}) (T, error) {
+	var zero T
+
+	select {
+	case slot <- struct{}{}:
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+
+	done := make(chan result[T], 1)
+
+	go func() {
+		defer func() { <-slot }()
+
+		value, err := op(ctx)
+		done <- result[T]{value: value, err: err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
+
+// DoShared is like Do(), but uses the Backoff shared by all callers using the
+// given key with the given registry. The key is used again with the registry
+// after each attempt, so that it doesn't expire while we're retrying. If op
+// eventually succeeds, the shared Backoff is Reset() via registry.Success(), so
+// that other callers retrying the same thing go back to sleeping for its Min.
+func DoShared(ctx context.Context, op Operation, until Until, registry *backoff.Registry,
+	key, activity string, observers ...Observer) *Status {
+	observers = append([]Observer{&registryUser{registry: registry, key: key}}, observers...)
+
+	status := Do(ctx, op, until, registry.Get(key), activity, observers...)
+	if status.Err == nil {
+		registry.Success(key)
+	}
+
+	return status
+}
+
+// registryUser is an Observer that Get()s a key from a Registry after every
+// attempt, so that the key's last use is kept up to date.
+type registryUser struct {
+	registry *backoff.Registry
+	key      string
+}
+
+// OnAttempt implements Observer.
+func (r *registryUser) OnAttempt(context.Context, int, Attempt) {
+	r.registry.Get(r.key)
+}
+
+// OnSleep implements Observer.
+func (r *registryUser) OnSleep(context.Context, int, time.Duration) {}
+
+// OnStop implements Observer.
+func (r *registryUser) OnStop(context.Context, *Status) {}
 
-	bo.Sleep(clog.ContextWithRetryNum(ctx, *retries))
+// attempt runs op inside a "retry.attempt" span, recording it in the given
+// history and returning its value and error.
+func attempt[T any](ctx context.Context, op ValueOperation[T], h *history) (T, error) {
+	ctx, endSpan := clog.StartSpan(ctx, "retry.attempt", "retrynum", h.status.Retried)
+	start := h.clock.Now()
+	value, err := op(ctx)
+	endSpan(err)
+	h.attempted(ctx, start, err)
 
-	return true
+	return value, err
 }
 
 // logStatusIfRetried logs the status if status.Retried > 0.
@@ -126,5 +313,5 @@ func logStatusIfRetried(ctx context.Context, status *Status) {
 		return
 	}
 
-	clog.Debug(ctx, "retried", "status", status.String())
+	clog.Named(logSubsystem).Debug(ctx, "retried", "status", status.String(), "elapsed", status.Elapsed())
 }
@@ -28,13 +28,19 @@ package retry
 import (
 	"context"
 	"errors"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	. "github.com/smartystreets/goconvey/convey"
 	"github.com/wtsi-ssg/wr/backoff"
 	bm "github.com/wtsi-ssg/wr/backoff/mock"
+	bt "github.com/wtsi-ssg/wr/backoff/time"
+	"github.com/wtsi-ssg/wr/clock"
+	cm "github.com/wtsi-ssg/wr/clock/mock"
 	"github.com/wtsi-ssg/wr/clog"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 var ErrOp = errors.New("op err")
@@ -83,6 +89,8 @@ func TestRetry(t *testing.T) {
 			So(lmsg, ShouldContainSubstring, "retrynum=2")
 			So(lmsg, ShouldContainSubstring, "msg=retried")
 			So(lmsg, ShouldContainSubstring, "status=\""+msg)
+			So(lmsg, ShouldContainSubstring, "subsystem=retry")
+			So(lmsg, ShouldContainSubstring, "elapsed=")
 		})
 	})
 
@@ -128,4 +136,351 @@ func TestRetry(t *testing.T) {
 
 		So(buff.String(), ShouldBeBlank)
 	})
+	Convey("Retries are recorded as spans", t, func() {
+		exporter := tracetest.NewInMemoryExporter()
+		clog.SetSpanExporter(exporter)
+		defer clog.SetTracerProvider(nil)
+
+		buff := clog.ToBufferAtLevel("debug")
+		defer clog.ToDefault()
+
+		backoff.Sleeper = &bm.Sleeper{}
+
+		status := Do(ctx, func() error { return ErrOp }, &UntilLimit{Max: 1}, backoff, activity)
+		So(status.Retried, ShouldEqual, 1)
+
+		spans := exporter.GetSpans()
+		So(len(spans), ShouldEqual, 4)
+
+		names := make([]string, len(spans))
+		for i, span := range spans {
+			names[i] = span.Name
+		}
+		So(names, ShouldResemble, []string{"retry.attempt", "backoff.sleep", "retry.attempt", "retry"})
+
+		parent := spans[3].SpanContext
+		for _, span := range spans[:3] {
+			So(span.Parent.SpanID(), ShouldEqual, parent.SpanID())
+			So(span.SpanContext.TraceID(), ShouldEqual, parent.TraceID())
+		}
+
+		So(spans[3].Status.Description, ShouldEqual, ErrOp.Error())
+		So(buff.String(), ShouldContainSubstring, "trace_id="+parent.TraceID().String())
+	})
+}
+
+func TestRetryStrategies(t *testing.T) {
+	Convey("You can Retry with any backoff Strategy", t, func() {
+		sleeper := &bm.Sleeper{}
+		linear := &backoff.Backoff{
+			Min:      time.Millisecond,
+			Max:      10 * time.Millisecond,
+			Strategy: backoff.Linear{},
+			Jitter:   backoff.JitterNone,
+			Sleeper:  sleeper,
+		}
+
+		status := Do(context.Background(), func() error { return ErrOp }, &UntilLimit{Max: 3}, linear, "doing foo")
+		So(status.Retried, ShouldEqual, 3)
+		So(sleeper.Elapsed(), ShouldEqual, 6*time.Millisecond)
+	})
+}
+
+func TestRetryClock(t *testing.T) {
+	Convey("Retries of hour-long backoffs can be driven by a mock clock in the context", t, func() {
+		start := time.Now()
+		mc := cm.New(start)
+		ctx := clock.ContextWithClock(context.Background(), mc)
+		hourly := &backoff.Backoff{Min: time.Hour, Max: time.Hour, Factor: 1, Sleeper: &bt.Sleeper{}}
+
+		go func() {
+			for range 3 {
+				mc.BlockUntil(1)
+				mc.Advance(time.Hour)
+			}
+		}()
+
+		status := Do(ctx, func() error { return ErrOp }, &UntilLimit{Max: 3}, hourly, "doing foo")
+		So(status.Retried, ShouldEqual, 3)
+		So(mc.Now(), ShouldEqual, start.Add(3*time.Hour))
+		So(time.Since(start), ShouldBeLessThan, time.Second)
+	})
+}
+
+func TestRetryShared(t *testing.T) {
+	Convey("Callers retrying with the same key of a Registry share a Backoff", t, func() {
+		reg := &backoff.Registry{New: func(string) *backoff.Backoff {
+			return &backoff.Backoff{Min: time.Millisecond, Max: time.Millisecond, Factor: 1, Sleeper: &bm.Sleeper{}}
+		}}
+
+		status := DoShared(context.Background(), func() error { return ErrOp }, &UntilLimit{Max: 2}, reg,
+			"host:1", "doing foo")
+		So(status.Err, ShouldNotBeNil)
+		So(reg.Get("host:1").State().Sleeps, ShouldEqual, 2)
+
+		fails := 1
+		status = DoShared(context.Background(), func() error {
+			if fails > 0 {
+				fails--
+
+				return ErrOp
+			}
+
+			return nil
+		}, &UntilLimit{Max: 2}, reg, "host:1", "doing foo")
+		So(status.Err, ShouldBeNil)
+		So(reg.Get("host:1").State().Sleeps, ShouldEqual, 0)
+	})
+
+	Convey("A shared Backoff doesn't expire while it is being retried with for longer than the TTL", t, func() {
+		mc := cm.New(time.Now())
+		made := 0
+		reg := &backoff.Registry{
+			New: func(string) *backoff.Backoff {
+				made++
+
+				return &backoff.Backoff{Min: 40 * time.Second, Max: 40 * time.Second, Factor: 1,
+					Sleeper: &bm.Sleeper{Clock: mc}}
+			},
+			TTL:   time.Minute,
+			Clock: mc,
+		}
+
+		status := DoShared(context.Background(), func() error { return ErrOp }, &UntilLimit{Max: 3}, reg,
+			"host:1", "doing foo")
+		So(status.Retried, ShouldEqual, 3)
+		So(reg.Get("host:1").State().Sleeps, ShouldEqual, 3)
+		So(made, ShouldEqual, 1)
+	})
+}
+
+func TestRetryDeadline(t *testing.T) {
+	Convey("Do() stops straight away when a sleep would pass the context's deadline", t, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		hourly := &backoff.Backoff{Min: time.Hour, Max: time.Hour, Factor: 1, Sleeper: &bt.Sleeper{}}
+		start := time.Now()
+		attempts := 0
+
+		status := Do(ctx, func() error {
+			attempts++
+
+			return ErrOp
+		}, &UntilLimit{Max: 3}, hourly, "doing foo")
+		So(status.StoppedBecause, ShouldEqual, BecauseContextClosed)
+		So(status.Retried, ShouldEqual, 0)
+		So(status.Err, ShouldEqual, ErrOp)
+		So(attempts, ShouldEqual, 1)
+		So(time.Since(start), ShouldBeLessThan, time.Second)
+	})
+}
+
+func TestRetryDoValue(t *testing.T) {
+	Convey("DoValue() returns the value of the last attempt along with the Status", t, func() {
+		ctx := context.Background()
+		bo := &backoff.Backoff{Min: time.Millisecond, Max: time.Millisecond, Factor: 1, Sleeper: &bm.Sleeper{}}
+		captured := clog.Capture(t, "debug")
+		attempts := 0
+
+		op := func(actx context.Context) (int, error) {
+			So(actx, ShouldNotBeNil)
+
+			attempts++
+			if attempts < 3 {
+				return attempts, ErrOp
+			}
+
+			return attempts * 10, nil
+		}
+
+		value, status := DoValue(ctx, op, &UntilNoError{}, bo, "doing foo")
+		So(value, ShouldEqual, 30)
+		So(status.Retried, ShouldEqual, 2)
+		So(status.StoppedBecause, ShouldEqual, BecauseErrorNil)
+		So(status.Err, ShouldBeNil)
+		So(captured.HasRecord("debug", "retried", "retryactivity", "doing foo",
+			"status", status.String()), ShouldBeTrue)
+
+		Convey("even if it failed", func() {
+			attempts = 0
+
+			value, status = DoValue(ctx, op, &UntilLimit{Max: 1}, bo, "doing foo")
+			So(value, ShouldEqual, 2)
+			So(status.StoppedBecause, ShouldEqual, BecauseLimitReached)
+			So(status.Err, ShouldEqual, ErrOp)
+		})
+	})
+}
+
+func TestRetryPermanent(t *testing.T) {
+	Convey("Do() with UntilPermanentError doesn't retry permanent errors", t, func() {
+		bo := &backoff.Backoff{Min: time.Millisecond, Max: time.Millisecond, Factor: 1, Sleeper: &bm.Sleeper{}}
+		until := Untils{&UntilPermanentError{}, &UntilLimit{Max: 3}}
+		attempts := 0
+
+		status := Do(context.Background(), func() error {
+			attempts++
+			if attempts == 2 {
+				return Permanent(ErrOp)
+			}
+
+			return ErrOp
+		}, until, bo, "doing foo")
+		So(status.StoppedBecause, ShouldEqual, BecausePermanentError)
+		So(status.Retried, ShouldEqual, 1)
+		So(errors.Is(status, ErrOp), ShouldBeTrue)
+	})
+}
+
+func TestRetryDoCtx(t *testing.T) {
+	background := context.Background()
+
+	Convey("DoCtx() cuts short and retries attempts that take longer than the timeout", t, func() {
+		bo := &backoff.Backoff{Min: time.Millisecond, Max: time.Millisecond, Factor: 1, Sleeper: &bm.Sleeper{}}
+		attempts := 0
+
+		status := DoCtx(background, func(ctx context.Context) error {
+			attempts++
+			if attempts == 1 {
+				<-ctx.Done()
+
+				return ctx.Err()
+			}
+
+			return nil
+		}, 10*time.Millisecond, &UntilNoError{}, bo, "doing foo")
+		So(status.StoppedBecause, ShouldEqual, BecauseErrorNil)
+		So(status.Retried, ShouldEqual, 1)
+
+		Convey("even if they ignore their context, without running them again while abandoned", func() {
+			hang := make(chan struct{})
+			defer close(hang)
+
+			var calls atomic.Int32
+
+			start := time.Now()
+
+			status = DoCtx(background, func(ctx context.Context) error {
+				calls.Add(1)
+				<-hang
+
+				return nil
+			}, 10*time.Millisecond, &UntilLimit{Max: 2}, bo, "doing foo")
+			So(status.StoppedBecause, ShouldEqual, BecauseLimitReached)
+			So(status.Retried, ShouldEqual, 2)
+			So(errors.Is(status, context.DeadlineExceeded), ShouldBeTrue)
+			So(calls.Load(), ShouldEqual, 1)
+			So(time.Since(start), ShouldBeLessThan, time.Second)
+		})
+	})
+
+	Convey("WithAttemptTimeout() runs op again once an abandoned run returns", t, func() {
+		hang := make(chan struct{})
+		calls := 0
+
+		op := WithAttemptTimeout(func(ctx context.Context) (int, error) {
+			calls++
+			if calls == 1 {
+				<-hang
+			}
+
+			return calls, nil
+		}, 10*time.Millisecond)
+
+		_, err := op(background)
+		So(errors.Is(err, context.DeadlineExceeded), ShouldBeTrue)
+
+		_, err = op(background)
+		So(errors.Is(err, context.DeadlineExceeded), ShouldBeTrue)
+
+		close(hang)
+
+		value, err := op(background)
+		So(err, ShouldBeNil)
+		So(value, ShouldEqual, 2)
+	})
+
+	Convey("WithAttemptTimeout() returns the op if there's no timeout", t, func() {
+		op := func(ctx context.Context) (int, error) {
+			_, hasDeadline := ctx.Deadline()
+			So(hasDeadline, ShouldBeFalse)
+
+			return 1, nil
+		}
+
+		value, err := WithAttemptTimeout(op, 0)(background)
+		So(value, ShouldEqual, 1)
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Do() with UntilElapsed stops after the time limit, according to the context's clock", t, func() {
+		mc := cm.New(time.Now())
+		ctx := clock.ContextWithClock(background, mc)
+		bo := &backoff.Backoff{Min: 3 * time.Second, Max: 3 * time.Second, Factor: 1, Sleeper: &bm.Sleeper{Clock: mc}}
+		limit := mc.Now().Add(10 * time.Second)
+
+		status := Do(ctx, func() error { return ErrOp }, &UntilElapsed{Total: 10 * time.Second}, bo, "doing foo")
+		So(status.StoppedBecause, ShouldEqual, BecauseTimeLimit)
+		So(status.Retried, ShouldEqual, 3)
+		So(mc.Now(), ShouldEqual, limit)
+
+		Convey("without starting an attempt after the limit", func() {
+			for _, a := range status.Attempts {
+				So(a.Start.After(limit), ShouldBeFalse)
+			}
+
+			So(status.Attempts[3].Sleep, ShouldEqual, time.Second)
+		})
+	})
+
+	Convey("Do() with UntilElapsed follows a mock clock that isn't at the current time", t, func() {
+		mc := cm.New(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+		ctx := clock.ContextWithClock(background, mc)
+		bo := &backoff.Backoff{Min: 3 * time.Second, Max: 3 * time.Second, Factor: 1, Sleeper: &bm.Sleeper{Clock: mc}}
+
+		status := Do(ctx, func() error { return ErrOp }, &UntilElapsed{Total: 10 * time.Second}, bo, "doing foo")
+		So(status.StoppedBecause, ShouldEqual, BecauseTimeLimit)
+		So(status.Retried, ShouldEqual, 3)
+		So(mc.Now(), ShouldEqual, time.Date(2020, 1, 1, 0, 0, 10, 0, time.UTC))
+	})
+
+	Convey("An UntilElapsed can be shared by concurrent Do()s", t, func() {
+		until := &UntilElapsed{Total: 10 * time.Second}
+		statuses := make([]*Status, 2)
+
+		var wg sync.WaitGroup
+
+		for i := range statuses {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				mc := cm.New(time.Now())
+				ctx := clock.ContextWithClock(background, mc)
+				bo := &backoff.Backoff{Min: 3 * time.Second, Max: 3 * time.Second, Factor: 1, Sleeper: &bm.Sleeper{Clock: mc}}
+				statuses[i] = Do(ctx, func() error { return ErrOp }, until, bo, "doing foo")
+			}()
+		}
+
+		wg.Wait()
+
+		for _, status := range statuses {
+			So(status.StoppedBecause, ShouldEqual, BecauseTimeLimit)
+			So(status.Retried, ShouldEqual, 3)
+		}
+	})
+
+	Convey("Do() with UntilElapsed stops because the context closed if its deadline is sooner", t, func() {
+		mc := cm.New(time.Now())
+		ctx, cancel := context.WithDeadline(clock.ContextWithClock(background, mc), mc.Now().Add(5*time.Second))
+		defer cancel()
+
+		bo := &backoff.Backoff{Min: 3 * time.Second, Max: 3 * time.Second, Factor: 1, Sleeper: &bm.Sleeper{Clock: mc}}
+
+		status := Do(ctx, func() error { return ErrOp }, &UntilElapsed{Total: 10 * time.Second}, bo, "doing foo")
+		So(status.StoppedBecause, ShouldEqual, BecauseContextClosed)
+		So(status.Retried, ShouldEqual, 1)
+	})
 }
@@ -25,17 +25,26 @@
 
 package retry
 
-import "context"
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/wtsi-ssg/wr/clock"
+)
 
 // Reason is the type of our Because* constants.
 type Reason string
 
 // Because* constants are returned by Until.ShouldStop().
 const (
-	BecauseLimitReached  Reason = "limit reached"
-	BecauseErrorNil      Reason = "there was no error"
-	BecauseContextClosed Reason = "context closed"
-	doNotStop            Reason = ""
+	BecauseLimitReached   Reason = "limit reached"
+	BecauseErrorNil       Reason = "there was no error"
+	BecauseContextClosed  Reason = "context closed"
+	BecausePermanentError Reason = "the error was permanent"
+	BecauseTimeLimit      Reason = "time limit reached"
+	BecauseCircuitOpen    Reason = "the circuit breaker was open"
+	doNotStop             Reason = ""
 )
 
 // Until is used by Retry to determine when to stop retrying.
@@ -46,10 +55,64 @@ type Until interface {
 	ShouldStop(retries int, err error) Reason
 }
 
+// binder is implemented by Untils that need their own state for each call of
+// Do().
+type binder interface {
+	// bind returns an Until to use for a call of Do() that starts now,
+	// according to the given clock.
+	bind(c clock.Clock) Until
+}
+
+// deadliner is implemented by Untils that stop retries at a certain time.
+type deadliner interface {
+	// deadline returns the time at which retries will be stopped.
+	deadline() time.Time
+}
+
 // Untils is a slice of Until which itself implements Until, letting you combine
 // multiple Untils.
 type Untils []Until
 
+// bind implements binder.
+func (u Untils) bind(c clock.Clock) Until {
+	return u.bound(c)
+}
+
+// bound returns a copy of us with any elements that need their own state bound
+// to a call of Do() that starts now, according to the given clock.
+func (u Untils) bound(c clock.Clock) Untils {
+	bound := make(Untils, len(u))
+
+	for i, until := range u {
+		if b, ok := until.(binder); ok {
+			until = b.bind(c)
+		}
+
+		bound[i] = until
+	}
+
+	return bound
+}
+
+// deadline implements deadliner, returning the earliest deadline of our
+// elements, or the zero time if none of them have one.
+func (u Untils) deadline() time.Time {
+	var earliest time.Time
+
+	for _, until := range u {
+		d, ok := until.(deadliner)
+		if !ok {
+			continue
+		}
+
+		if t := d.deadline(); !t.IsZero() && (earliest.IsZero() || t.Before(earliest)) {
+			earliest = t
+		}
+	}
+
+	return earliest
+}
+
 // ShouldStop returns a non-blank Reason when any of the elements of this
 // slice return one.
 func (u Untils) ShouldStop(retries int, err error) Reason {
@@ -93,6 +156,110 @@ func (u *UntilNoError) ShouldStop(retries int, err error) Reason {
 	return doNotStop
 }
 
+// UntilPermanentError implements Until, stopping retries when the error passed
+// to ShouldStop is classified as permanent by IsPermanent(), eg. because it was
+// wrapped with Permanent().
+type UntilPermanentError struct{}
+
+// ShouldStop returns BecausePermanentError when IsPermanent(err). retries is
+// not considered.
+func (u *UntilPermanentError) ShouldStop(retries int, err error) Reason {
+	if IsPermanent(err) {
+		return BecausePermanentError
+	}
+
+	return doNotStop
+}
+
+// UntilErrorIs implements Until, treating errors that match Target according
+// to errors.Is() as permanent.
+type UntilErrorIs struct {
+	Target error
+}
+
+// ShouldStop returns BecausePermanentError when errors.Is(err, Target).
+// retries is not considered.
+func (u *UntilErrorIs) ShouldStop(retries int, err error) Reason {
+	if err != nil && errors.Is(err, u.Target) {
+		return BecausePermanentError
+	}
+
+	return doNotStop
+}
+
+// UntilErrorAs implements Until, treating errors that have an error of type T
+// in their chain according to errors.As() as permanent. Eg.
+// &UntilErrorAs[*fs.PathError]{}.
+type UntilErrorAs[T error] struct{}
+
+// ShouldStop returns BecausePermanentError when errors.As(err, *T). retries is
+// not considered.
+func (u *UntilErrorAs[T]) ShouldStop(retries int, err error) Reason {
+	var target T
+	if errors.As(err, &target) {
+		return BecausePermanentError
+	}
+
+	return doNotStop
+}
+
+// UntilElapsed implements Until, stopping retries once Total time has passed
+// since Do() (or one of its variants) started, according to the clock.Clock of
+// the context passed to Do() (see clock.ContextWithClock()). The backoff sleep
+// after an attempt is cut short at that time, so no attempt starts after Total,
+// though the last attempt may end after it.
+//
+// Each Do() call keeps its own start time, so the same UntilElapsed can be used
+// by concurrent calls. If you call ShouldStop() yourself instead, time is
+// measured in real time from the first call, and it is not safe for concurrent
+// use.
+type UntilElapsed struct {
+	Total time.Duration
+
+	started time.Time
+}
+
+// bind implements binder.
+func (u *UntilElapsed) bind(c clock.Clock) Until {
+	return &untilTime{clock: c, end: c.Now().Add(u.Total)}
+}
+
+// ShouldStop returns BecauseTimeLimit when Total time has passed since the
+// first call to ShouldStop. retries and err are not considered.
+func (u *UntilElapsed) ShouldStop(retries int, err error) Reason {
+	if u.started.IsZero() {
+		u.started = time.Now()
+	}
+
+	if time.Since(u.started) >= u.Total {
+		return BecauseTimeLimit
+	}
+
+	return doNotStop
+}
+
+// untilTime implements Until for a single call of Do(), stopping retries at a
+// certain time according to a clock.
+type untilTime struct {
+	clock clock.Clock
+	end   time.Time
+}
+
+// ShouldStop returns BecauseTimeLimit once it is our end time. retries and err
+// are not considered.
+func (u *untilTime) ShouldStop(retries int, err error) Reason {
+	if !u.clock.Now().Before(u.end) {
+		return BecauseTimeLimit
+	}
+
+	return doNotStop
+}
+
+// deadline implements deadliner.
+func (u *untilTime) deadline() time.Time {
+	return u.end
+}
+
 // untilContext implements Until, stopping retries after the context has been
 // closed.
 type untilContext struct {
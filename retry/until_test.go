@@ -28,9 +28,14 @@ package retry
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io/fs"
+	"os"
 	"testing"
+	"time"
 
 	. "github.com/smartystreets/goconvey/convey"
+	cm "github.com/wtsi-ssg/wr/clock/mock"
 )
 
 var ErrNormal = errors.New("normal")
@@ -55,6 +60,70 @@ func TestUntil(t *testing.T) {
 		So(u.ShouldStop(1, nil), ShouldEqual, BecauseErrorNil)
 	})
 
+	Convey("UntilPermanentError stops after getting a permanent error", t, func() {
+		var _ Until = (*UntilPermanentError)(nil)
+		u := &UntilPermanentError{}
+		So(u.ShouldStop(0, nil), ShouldEqual, doNotStop)
+		So(u.ShouldStop(0, ErrNormal), ShouldEqual, doNotStop)
+		So(u.ShouldStop(0, Transient(ErrNormal)), ShouldEqual, doNotStop)
+		So(u.ShouldStop(0, Permanent(ErrNormal)), ShouldEqual, BecausePermanentError)
+	})
+
+	Convey("UntilErrorIs stops after getting an error that is its Target", t, func() {
+		var _ Until = (*UntilErrorIs)(nil)
+		u := &UntilErrorIs{Target: ErrNormal}
+		So(u.ShouldStop(0, nil), ShouldEqual, doNotStop)
+		So(u.ShouldStop(0, errors.New("other")), ShouldEqual, doNotStop)
+		So(u.ShouldStop(0, ErrNormal), ShouldEqual, BecausePermanentError)
+		So(u.ShouldStop(0, fmt.Errorf("wrapped: %w", ErrNormal)), ShouldEqual, BecausePermanentError)
+	})
+
+	Convey("UntilErrorAs stops after getting an error of its type", t, func() {
+		var _ Until = (*UntilErrorAs[*fs.PathError])(nil)
+		u := &UntilErrorAs[*fs.PathError]{}
+		So(u.ShouldStop(0, nil), ShouldEqual, doNotStop)
+		So(u.ShouldStop(0, ErrNormal), ShouldEqual, doNotStop)
+
+		_, err := os.Open("/non/existent")
+		So(u.ShouldStop(0, fmt.Errorf("wrapped: %w", err)), ShouldEqual, BecausePermanentError)
+	})
+
+	Convey("UntilElapsed stops after Total time has passed since a Do() started", t, func() {
+		var _ Until = (*UntilElapsed)(nil)
+		mc := cm.New(time.Now())
+		u := &UntilElapsed{Total: time.Minute}
+		bound := Untils{u}.bound(mc)
+		So(bound.ShouldStop(0, ErrNormal), ShouldEqual, doNotStop)
+		So(bound.deadline(), ShouldEqual, mc.Now().Add(time.Minute))
+
+		mc.Advance(59 * time.Second)
+		So(bound.ShouldStop(1, ErrNormal), ShouldEqual, doNotStop)
+
+		other := Untils{u}.bound(mc)
+
+		mc.Advance(time.Second)
+		So(bound.ShouldStop(2, nil), ShouldEqual, BecauseTimeLimit)
+		So(other.ShouldStop(0, nil), ShouldEqual, doNotStop)
+		So(u.started.IsZero(), ShouldBeTrue)
+
+		Convey("or since its first use, if used directly", func() {
+			u = &UntilElapsed{Total: time.Hour}
+			So(u.ShouldStop(0, ErrNormal), ShouldEqual, doNotStop)
+			So((&UntilElapsed{}).ShouldStop(0, ErrNormal), ShouldEqual, BecauseTimeLimit)
+		})
+	})
+
+	Convey("Untils have the earliest deadline of their elements", t, func() {
+		mc := cm.New(time.Now())
+		So(Untils{&UntilLimit{}}.bound(mc).deadline().IsZero(), ShouldBeTrue)
+
+		bound := Untils{
+			&UntilElapsed{Total: time.Hour},
+			&Untils{&UntilLimit{}, &UntilElapsed{Total: time.Minute}},
+		}.bound(mc)
+		So(bound.deadline(), ShouldEqual, mc.Now().Add(time.Minute))
+	})
+
 	Convey("untilContext stops after the context is done", t, func() {
 		var _ Until = (*untilContext)(nil)
 		ctx, cancel := context.WithCancel(context.Background())